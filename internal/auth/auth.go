@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -59,10 +60,11 @@ func NewClient(cfg *config.Config) *Client {
 	return client
 }
 
-func (c *Client) Login(opts LoginOptions) error {
+func (c *Client) Login(ctx context.Context, opts LoginOptions) error {
 	authHeader := c.createAuthHeader(opts.Username, opts.Password)
 	
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetHeader("Authorization", authHeader).
 		SetResult(&AuthResponse{}).
 		Get("/auth/user")
@@ -90,8 +92,9 @@ func (c *Client) Login(opts LoginOptions) error {
 
 
 // VerifyTOTPCode verifies TOTP code programmatically (for GUI use)
-func (c *Client) VerifyTOTPCode(code string) error {
+func (c *Client) VerifyTOTPCode(ctx context.Context, code string) error {
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetBody(map[string]string{"code": code}).
 		SetResult(&TwoFactorAuthResponse{}).
 		Post("/auth/twofactorauth/totp/verify")
@@ -115,8 +118,9 @@ func (c *Client) VerifyTOTPCode(code string) error {
 
 
 // VerifyRecoveryCode verifies recovery code programmatically (for GUI use)
-func (c *Client) VerifyRecoveryCode(code string) error {
+func (c *Client) VerifyRecoveryCode(ctx context.Context, code string) error {
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetBody(map[string]string{"code": code}).
 		SetResult(&TwoFactorAuthResponse{}).
 		Post("/auth/twofactorauth/recoverycode/verify")
@@ -151,8 +155,9 @@ func (c *Client) IsAuthenticated() bool {
 	return true
 }
 
-func (c *Client) GetCurrentUser() (*User, error) {
+func (c *Client) GetCurrentUser(ctx context.Context) (*User, error) {
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetResult(&User{}).
 		Get("/auth/user")
 
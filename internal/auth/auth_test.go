@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"testing"
@@ -174,7 +176,7 @@ func TestLogin_Success(t *testing.T) {
 		Password: "testpass",
 	}
 
-	err = client.Login(opts)
+	err = client.Login(context.Background(), opts)
 	assert.NoError(t, err)
 
 	// Verify auth cookie was saved
@@ -280,7 +282,7 @@ func TestVerifyTOTPCode(t *testing.T) {
 					httpmock.NewStringResponder(tt.mockStatusCode, "Server error"))
 			}
 
-			err = client.VerifyTOTPCode(tt.code)
+			err = client.VerifyTOTPCode(context.Background(), tt.code)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -340,7 +342,7 @@ func TestVerifyRecoveryCode(t *testing.T) {
 			return resp, nil
 		})
 
-	err = client.VerifyRecoveryCode("1234-5678-9012")
+	err = client.VerifyRecoveryCode(context.Background(), "1234-5678-9012")
 	assert.NoError(t, err)
 
 	// Verify auth cookie was saved
@@ -397,7 +399,7 @@ func TestGetCurrentUser(t *testing.T) {
 			return resp, nil
 		})
 
-	user, err := client.GetCurrentUser()
+	user, err := client.GetCurrentUser(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, user)
 
@@ -407,6 +409,26 @@ func TestGetCurrentUser(t *testing.T) {
 	assert.Equal(t, mockUser.TwoFactorAuthEnabled, user.TwoFactorAuthEnabled)
 }
 
+func TestGetCurrentUser_Cancelled(t *testing.T) {
+	// Server that never answers until the client gives up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIBaseURL: server.URL,
+	}
+	client := NewClient(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	user, err := client.GetCurrentUser(ctx)
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestLogout(t *testing.T) {
 	// Create temporary home directory for config
 	tempHome := t.TempDir()
@@ -0,0 +1,414 @@
+// Package backup keeps a local archive of a user's prints: the full-size
+// images plus a manifest of their details in JSON and CSV
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yoshiken/vrc-print-upload/internal/client"
+	"github.com/yoshiken/vrc-print-upload/internal/prints"
+)
+
+const (
+	// ManifestFile and ManifestCSVFile are the manifest files in the
+	// archive directory
+	ManifestFile    = "manifest.json"
+	ManifestCSVFile = "manifest.csv"
+	// ImageDir is the directory of the images, relative to the archive
+	ImageDir = "images"
+
+	// maxRetries is how often a request is retried while the API asks us
+	// to wait, so that an outage doesn't keep a run waiting forever
+	maxRetries = 5
+)
+
+// printIDPattern matches the print IDs the API hands out, such as
+// prnt_1b2c3d4e-…, which are used as file names
+var printIDPattern = regexp.MustCompile(`^prnt_[0-9A-Za-z-]+$`)
+
+// Manifest lists the prints in an archive
+type Manifest struct {
+	UserID    string    `json:"userId"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Prints are sorted newest first
+	Prints []Entry `json:"prints"`
+}
+
+// Entry is an archived print. Prints deleted from the account stay in the
+// archive.
+type Entry struct {
+	prints.Print
+	// File is the path of the image, relative to the archive directory
+	File         string    `json:"file"`
+	Size         int64     `json:"size"`
+	DownloadedAt time.Time `json:"downloadedAt"`
+}
+
+// Phase is the stage a backup run is in
+type Phase string
+
+const (
+	PhaseListing     Phase = "listing"
+	PhaseDownloading Phase = "downloading"
+	// PhaseWaiting is reported while the API asks us to hold back
+	PhaseWaiting Phase = "waiting"
+)
+
+// Progress reports how far a backup run has got
+type Progress struct {
+	Phase Phase
+	// Done and Total count the prints listed so far while listing, and
+	// the new prints downloaded so far while downloading
+	Done  int
+	Total int
+	// PrintID is the print being downloaded
+	PrintID string
+	// Wait is how long the run is waiting for the rate limit to reset
+	Wait time.Duration
+}
+
+// Result summarizes a backup run
+type Result struct {
+	// Listed is the number of prints on the account
+	Listed int
+	// Downloaded is the number of images downloaded by this run; Skipped
+	// were already in the archive
+	Downloaded int
+	Skipped    int
+	// Failed are the prints whose image couldn't be downloaded, by ID; the
+	// next run tries them again
+	Failed map[string]error
+	// Bytes is the size of the downloaded images
+	Bytes int64
+	// Archived is the number of prints in the archive, including ones
+	// deleted from the account
+	Archived int
+}
+
+// Archive is a backup of the prints of one user in a directory. Every
+// downloaded image is recorded in the manifest right away, so a run that
+// is interrupted resumes where it stopped.
+type Archive struct {
+	dir     string
+	service *prints.Service
+	// sleep waits for d unless ctx ends first; replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewArchive(dir string, service *prints.Service) *Archive {
+	return &Archive{
+		dir:     dir,
+		service: service,
+		sleep:   sleep,
+	}
+}
+
+// Dir returns the archive directory
+func (a *Archive) Dir() string {
+	return a.dir
+}
+
+// Manifest reads the manifest of the archive. An archive that doesn't
+// exist yet has an empty manifest.
+func (a *Archive) Manifest() (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(a.dir, ManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Manifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Sync lists all prints of the user and downloads the images that aren't
+// in the archive yet, or whose file has gone missing. The details of
+// prints already in the archive are refreshed from the listing.
+//
+// Requests go through the API client's rate limiter. When it gives up
+// because the API asked for a longer wait, Sync waits that long and
+// retries instead of failing.
+func (a *Archive) Sync(ctx context.Context, userID string, progress func(Progress)) (*Result, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	if progress == nil {
+		progress = func(Progress) {}
+	}
+
+	manifest, err := a.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	if manifest.UserID != "" && manifest.UserID != userID {
+		return nil, fmt.Errorf("archive %s belongs to user %s", a.dir, manifest.UserID)
+	}
+	manifest.UserID = userID
+
+	if err := os.MkdirAll(filepath.Join(a.dir, ImageDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	listed, err := a.list(ctx, userID, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]Entry, len(manifest.Prints))
+	for _, e := range manifest.Prints {
+		entries[e.ID] = e
+	}
+
+	result := &Result{Listed: len(listed)}
+	var pending []prints.Print
+	for _, p := range listed {
+		entry, ok := entries[p.ID]
+		if ok && a.exists(entry) {
+			entry.Print = p
+			entries[p.ID] = entry
+			result.Skipped++
+			continue
+		}
+		pending = append(pending, p)
+	}
+
+	// Save the refreshed details before downloading anything
+	if err := a.save(manifest, entries); err != nil {
+		return nil, err
+	}
+
+	for i, p := range pending {
+		progress(Progress{Phase: PhaseDownloading, Done: i, Total: len(pending), PrintID: p.ID})
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		entry, err := a.download(ctx, p, progress)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, err
+			}
+			if result.Failed == nil {
+				result.Failed = map[string]error{}
+			}
+			result.Failed[p.ID] = err
+			continue
+		}
+		entries[p.ID] = *entry
+		if err := a.save(manifest, entries); err != nil {
+			return result, err
+		}
+		result.Downloaded++
+		result.Bytes += entry.Size
+	}
+	progress(Progress{Phase: PhaseDownloading, Done: len(pending), Total: len(pending)})
+
+	result.Archived = len(entries)
+	return result, nil
+}
+
+// list returns all prints of the user, page by page
+func (a *Archive) list(ctx context.Context, userID string, progress func(Progress)) ([]prints.Print, error) {
+	var all []prints.Print
+	seen := map[string]bool{}
+	opts := prints.ListOptions{Limit: prints.MaxPageSize}
+	for {
+		progress(Progress{Phase: PhaseListing, Done: len(all)})
+
+		var page []prints.Print
+		err := a.retry(ctx, progress, func() error {
+			var err error
+			page, err = a.service.List(ctx, userID, opts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prints: %w", err)
+		}
+
+		// A print uploaded during the run shifts the pages, which would
+		// list the last print of a page again
+		for _, p := range page {
+			if !seen[p.ID] {
+				seen[p.ID] = true
+				all = append(all, p)
+			}
+		}
+		if len(page) < opts.PageSize() {
+			return all, nil
+		}
+		opts.Offset += len(page)
+	}
+}
+
+// download saves the image of p in the archive. The image is written to a
+// temporary file first, so an interrupted download leaves no partial image
+// behind.
+func (a *Archive) download(ctx context.Context, p prints.Print, progress func(Progress)) (*Entry, error) {
+	name, err := imageName(p)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(ImageDir, name)
+	path := filepath.Join(a.dir, file)
+
+	var size int64
+	err = a.retry(ctx, progress, func() error {
+		out, err := os.Create(path + ".part")
+		if err != nil {
+			return fmt.Errorf("failed to create image file: %w", err)
+		}
+		size, err = a.service.DownloadImage(ctx, p, out)
+		if closeErr := out.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write image file: %w", closeErr)
+		}
+		if err != nil {
+			os.Remove(path + ".part")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download print %s: %w", p.ID, err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		return nil, fmt.Errorf("failed to write image file: %w", err)
+	}
+
+	return &Entry{
+		Print:        p,
+		File:         filepath.ToSlash(file),
+		Size:         size,
+		DownloadedAt: time.Now(),
+	}, nil
+}
+
+// retry runs fn until it succeeds, fails with an error other than the API
+// asking us to wait, or has been retried maxRetries times
+func (a *Archive) retry(ctx context.Context, progress func(Progress), fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		wait, ok := retryAfter(err)
+		if !ok || attempt == maxRetries {
+			return err
+		}
+		progress(Progress{Phase: PhaseWaiting, Wait: wait})
+		if err := a.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// retryAfter returns how long to wait before retrying a request that
+// failed with err, if it failed because of the rate limit or an outage
+func retryAfter(err error) (time.Duration, bool) {
+	var rateLimitErr *client.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, true
+	}
+	var unavailableErr *client.APIUnavailableError
+	if errors.As(err, &unavailableErr) {
+		return unavailableErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// exists reports whether the image of an archived print is still there
+func (a *Archive) exists(entry Entry) bool {
+	info, err := os.Stat(filepath.Join(a.dir, filepath.FromSlash(entry.File)))
+	return err == nil && info.Size() == entry.Size
+}
+
+// save writes the manifest with the given entries as JSON and CSV. The
+// files are replaced atomically so that an interrupted run never leaves a
+// broken manifest.
+func (a *Archive) save(manifest *Manifest, entries map[string]Entry) error {
+	manifest.Prints = make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		manifest.Prints = append(manifest.Prints, e)
+	}
+	sort.Slice(manifest.Prints, func(i, j int) bool {
+		a, b := manifest.Prints[i], manifest.Prints[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	manifest.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(filepath.Join(a.dir, ManifestFile), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "file", "note", "worldId", "worldName", "authorId", "authorName", "timestamp", "createdAt", "size"})
+	for _, e := range manifest.Prints {
+		w.Write([]string{
+			e.ID, e.File, e.Note, e.WorldID, e.WorldName, e.AuthorID, e.AuthorName,
+			formatTime(e.Timestamp), formatTime(e.CreatedAt), strconv.FormatInt(e.Size, 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(filepath.Join(a.dir, ManifestCSVFile), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// writeFile replaces the file at path with data by way of a temporary file
+func writeFile(path string, data []byte) error {
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// imageName is the file name of the image of p. Print images are PNGs.
+// The ID comes from the server, so anything that could leave the image
+// directory is rejected.
+func imageName(p prints.Print) (string, error) {
+	if !printIDPattern.MatchString(p.ID) {
+		return "", fmt.Errorf("invalid print ID %q", p.ID)
+	}
+	return p.ID + ".png", nil
+}
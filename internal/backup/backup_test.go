@@ -0,0 +1,314 @@
+package backup
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoshiken/vrc-print-upload/internal/client"
+	"github.com/yoshiken/vrc-print-upload/internal/prints"
+)
+
+const baseURL = "https://api.vrchat.cloud/api/1"
+
+// fakeAPI serves the prints of usr_12345, newest first, and their images
+type fakeAPI struct {
+	prints []prints.Print
+	// failing images return 500
+	failing map[string]bool
+	// limited images fail once with a rate limit error
+	limited map[string]bool
+	// downloads counts the image requests per print
+	downloads map[string]int
+}
+
+func newFakeAPI(n int) *fakeAPI {
+	api := &fakeAPI{failing: map[string]bool{}, limited: map[string]bool{}, downloads: map[string]int{}}
+	for i := 0; i < n; i++ {
+		api.add()
+	}
+	return api
+}
+
+// add uploads a new print
+func (f *fakeAPI) add() prints.Print {
+	i := len(f.prints)
+	id := fmt.Sprintf("prnt_%03d", i)
+	p := prints.Print{
+		ID:         id,
+		AuthorID:   "usr_12345",
+		AuthorName: "TestUser",
+		Note:       fmt.Sprintf("note, %d", i),
+		WorldID:    "wrld_12345",
+		WorldName:  "Test World",
+		Timestamp:  time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC),
+		CreatedAt:  time.Date(2024, 1, 2, 0, i, 0, 0, time.UTC),
+		Files:      prints.Files{FileID: "file_" + id, Image: baseURL + "/file/file_" + id + "/1/file"},
+	}
+	f.prints = append([]prints.Print{p}, f.prints...)
+	return p
+}
+
+func (f *fakeAPI) register() {
+	httpmock.RegisterResponder("GET", baseURL+"/prints/user/usr_12345", func(req *http.Request) (*http.Response, error) {
+		n, _ := strconv.Atoi(req.URL.Query().Get("n"))
+		offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+		page := []prints.Print{}
+		if offset < len(f.prints) {
+			page = f.prints[offset:min(offset+n, len(f.prints))]
+		}
+		return httpmock.NewJsonResponse(200, page)
+	})
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/file/file_(prnt_\d+)/1/file$`), func(req *http.Request) (*http.Response, error) {
+		id := httpmock.MustGetSubmatch(req, 1)
+		f.downloads[id]++
+		if f.limited[id] {
+			f.limited[id] = false
+			return nil, &client.RateLimitError{RetryAfter: time.Minute}
+		}
+		if f.failing[id] {
+			return httpmock.NewStringResponse(500, "internal error"), nil
+		}
+		return httpmock.NewStringResponse(200, "image of "+id), nil
+	})
+}
+
+func newTestArchive(t *testing.T, dir string) *Archive {
+	c := resty.New()
+	c.SetBaseURL(baseURL)
+	httpmock.ActivateNonDefault(c.GetClient())
+	t.Cleanup(httpmock.DeactivateAndReset)
+
+	archive := NewArchive(dir, prints.New(c))
+	archive.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	return archive
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, dir)
+	api := newFakeAPI(130)
+	api.register()
+
+	var phases []Phase
+	result, err := archive.Sync(context.Background(), "usr_12345", func(p Progress) {
+		if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+			phases = append(phases, p.Phase)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Listed: 130, Downloaded: 130, Bytes: int64(130 * len("image of prnt_000")), Archived: 130}, result)
+	assert.Equal(t, []Phase{PhaseListing, PhaseDownloading}, phases)
+
+	data, err := os.ReadFile(filepath.Join(dir, "images", "prnt_042.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "image of prnt_042", string(data))
+
+	manifest, err := archive.Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, "usr_12345", manifest.UserID)
+	require.Len(t, manifest.Prints, 130)
+	first := manifest.Prints[0]
+	assert.Equal(t, "prnt_129", first.ID)
+	assert.Equal(t, "images/prnt_129.png", first.File)
+	assert.Equal(t, "note, 129", first.Note)
+	assert.Equal(t, "Test World", first.WorldName)
+	assert.Equal(t, "TestUser", first.AuthorName)
+	assert.True(t, time.Date(2024, 1, 1, 2, 9, 0, 0, time.UTC).Equal(first.Timestamp))
+	assert.Equal(t, int64(len("image of prnt_129")), first.Size)
+	assert.False(t, first.DownloadedAt.IsZero())
+
+	file, err := os.Open(filepath.Join(dir, "manifest.csv"))
+	require.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 131)
+	assert.Equal(t, []string{"id", "file", "note", "worldId", "worldName", "authorId", "authorName", "timestamp", "createdAt", "size"}, records[0])
+	assert.Equal(t, []string{"prnt_129", "images/prnt_129.png", "note, 129", "wrld_12345", "Test World", "usr_12345", "TestUser",
+		"2024-01-01T02:09:00Z", "2024-01-02T02:09:00Z", "17"}, records[1])
+
+	// No temporary files are left behind
+	matches, err := filepath.Glob(filepath.Join(dir, "*", "*.part"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	_, err = os.Stat(filepath.Join(dir, "manifest.json.tmp"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSync_Incremental(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, dir)
+	api := newFakeAPI(3)
+	api.register()
+
+	_, err := archive.Sync(context.Background(), "usr_12345", nil)
+	require.NoError(t, err)
+
+	// A new print, an edited note, a deleted print and a lost image
+	api.add()
+	api.prints[1].Note = "edited"
+	deleted := api.prints[3]
+	api.prints = api.prints[:3]
+	require.NoError(t, os.Remove(filepath.Join(dir, "images", "prnt_001.png")))
+
+	result, err := archive.Sync(context.Background(), "usr_12345", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Listed)
+	assert.Equal(t, 2, result.Downloaded)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, 4, result.Archived)
+	assert.Equal(t, map[string]int{"prnt_000": 1, "prnt_001": 2, "prnt_002": 1, "prnt_003": 1}, api.downloads)
+
+	manifest, err := archive.Manifest()
+	require.NoError(t, err)
+	ids := []string{}
+	for _, e := range manifest.Prints {
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []string{"prnt_003", "prnt_002", "prnt_001", deleted.ID}, ids)
+	assert.Equal(t, "edited", manifest.Prints[1].Note)
+	assert.FileExists(t, filepath.Join(dir, "images", "prnt_001.png"))
+}
+
+func TestSync_Resume(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, dir)
+	api := newFakeAPI(3)
+	api.failing["prnt_001"] = true
+	api.register()
+
+	result, err := archive.Sync(context.Background(), "usr_12345", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Downloaded)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed["prnt_001"].Error(), "download image failed with status 500")
+	assert.NoFileExists(t, filepath.Join(dir, "images", "prnt_001.png"))
+	assert.NoFileExists(t, filepath.Join(dir, "images", "prnt_001.png.part"))
+
+	// An interrupted run keeps what it downloaded
+	ctx, cancel := context.WithCancel(context.Background())
+	api.failing["prnt_001"] = false
+	api.add()
+	progress := func(p Progress) {
+		if p.Phase == PhaseDownloading && p.Done == 1 {
+			cancel()
+		}
+	}
+	result, err = archive.Sync(ctx, "usr_12345", progress)
+	require.Error(t, err)
+	assert.Equal(t, 1, result.Downloaded)
+
+	result, err = archive.Sync(context.Background(), "usr_12345", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Downloaded)
+	assert.Equal(t, 3, result.Skipped)
+	assert.Empty(t, result.Failed)
+	for id, n := range api.downloads {
+		if id != "prnt_001" {
+			assert.Equal(t, 1, n, id)
+		}
+	}
+}
+
+func TestSync_RateLimited(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, dir)
+	var waited []time.Duration
+	archive.sleep = func(ctx context.Context, d time.Duration) error {
+		waited = append(waited, d)
+		return nil
+	}
+
+	api := newFakeAPI(2)
+	api.register()
+	api.limited["prnt_000"] = true
+
+	var waits []time.Duration
+	result, err := archive.Sync(context.Background(), "usr_12345", func(p Progress) {
+		if p.Phase == PhaseWaiting {
+			waits = append(waits, p.Wait)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Downloaded)
+	assert.Equal(t, []time.Duration{time.Minute}, waited)
+	assert.Equal(t, []time.Duration{time.Minute}, waits)
+}
+
+func TestSync_Outage(t *testing.T) {
+	archive := newTestArchive(t, t.TempDir())
+	var waited int
+	archive.sleep = func(ctx context.Context, d time.Duration) error {
+		waited++
+		return nil
+	}
+
+	// The API stays down, so the run gives up after a few waits
+	httpmock.RegisterResponder("GET", baseURL+"/prints/user/usr_12345", func(req *http.Request) (*http.Response, error) {
+		return nil, &client.APIUnavailableError{RetryAfter: 30 * time.Second}
+	})
+	_, err := archive.Sync(context.Background(), "usr_12345", nil)
+	var unavailableErr *client.APIUnavailableError
+	require.ErrorAs(t, err, &unavailableErr)
+	assert.Equal(t, maxRetries, waited)
+	assert.Equal(t, maxRetries+1, httpmock.GetTotalCallCount())
+}
+
+func TestSync_InvalidID(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, filepath.Join(dir, "archive"))
+	api := newFakeAPI(2)
+	api.prints[0].ID = "prnt_../../evil"
+	api.register()
+
+	// A print whose ID isn't a file name is never downloaded
+	result, err := archive.Sync(context.Background(), "usr_12345", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Downloaded)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed["prnt_../../evil"].Error(), "invalid print ID")
+	assert.NoFileExists(t, filepath.Join(dir, "evil.png"))
+	assert.Equal(t, map[string]int{"prnt_000": 1}, api.downloads)
+}
+
+func TestSync_Errors(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, dir)
+	api := newFakeAPI(1)
+	api.register()
+
+	_, err := archive.Sync(context.Background(), "", nil)
+	assert.Error(t, err)
+
+	_, err = archive.Sync(context.Background(), "usr_12345", nil)
+	require.NoError(t, err)
+
+	// The archive belongs to another user
+	_, err = archive.Sync(context.Background(), "usr_67890", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "belongs to user usr_12345")
+
+	// Listing fails
+	httpmock.RegisterResponder("GET", baseURL+"/prints/user/usr_12345", httpmock.NewStringResponder(401, "unauthorized"))
+	_, err = archive.Sync(context.Background(), "usr_12345", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list prints")
+
+	// A broken manifest isn't overwritten
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{"), 0644))
+	_, err = archive.Sync(context.Background(), "usr_12345", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse manifest")
+}
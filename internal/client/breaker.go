@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets all requests through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails requests fast without contacting the API
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single trial request through after the cooldown
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// APIUnavailableError is returned while the circuit breaker is open
+type APIUnavailableError struct {
+	RetryAfter time.Duration
+}
+
+func (e *APIUnavailableError) Error() string {
+	return fmt.Sprintf("API unavailable after repeated failures: retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// BreakerStatus is a snapshot of the circuit breaker
+type BreakerStatus struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	// RetryAt is when an open breaker lets the next trial request through
+	RetryAt time.Time
+}
+
+// CircuitBreaker stops sending requests after repeated server or
+// connection failures, so that outages aren't made worse by retries.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	trialSent bool
+	// trialEnd is when the trial request in flight gives up
+	trialEnd  time.Time
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold
+// consecutive failures and half-opens after cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		state:     BreakerClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent, returning an
+// APIUnavailableError if not.
+func (b *CircuitBreaker) Allow() error {
+	return b.allow(time.Time{})
+}
+
+// allow is Allow for a request that gives up at deadline. A trial request
+// without a deadline is given the cooldown to finish.
+func (b *CircuitBreaker) allow(deadline time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	if b.state == BreakerOpen {
+		retryAt := b.openedAt.Add(b.cooldown)
+		if now.Before(retryAt) {
+			return &APIUnavailableError{RetryAfter: retryAt.Sub(now)}
+		}
+		b.state = BreakerHalfOpen
+		b.trialSent = false
+	}
+
+	if b.state == BreakerHalfOpen {
+		// Only one trial request at a time
+		if b.trialSent {
+			return &APIUnavailableError{RetryAfter: max(b.trialEnd.Sub(now), 0)}
+		}
+		b.trialSent = true
+		b.trialEnd = deadline
+		if deadline.IsZero() {
+			b.trialEnd = now.Add(b.cooldown)
+		}
+	}
+
+	return nil
+}
+
+// Record reports the outcome of a request that Allow let through
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release frees a half-open trial slot without recording an outcome
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialSent = false
+}
+
+// Status returns the current breaker state
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state == BreakerOpen {
+		status.RetryAt = b.openedAt.Add(b.cooldown)
+		if !b.now().Before(status.RetryAt) {
+			// The next request will be let through as a trial
+			status.State = BreakerHalfOpen
+		}
+	}
+	return status
+}
+
+// breakerTransport runs every request attempt, including retries, through
+// the circuit breaker.
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *CircuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The request context carries the client timeout
+	deadline, _ := req.Context().Deadline()
+	if err := t.breaker.allow(deadline); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	switch {
+	case err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)):
+		// The caller gave up; that says nothing about the API. Release the
+		// trial slot without changing the state.
+		t.breaker.release()
+	case err != nil:
+		t.breaker.Record(false)
+	default:
+		t.breaker.Record(resp.StatusCode < 500)
+	}
+	return resp, err
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"errors"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -11,39 +12,81 @@ const (
 	maxRetries     = 3
 	retryWaitTime  = 1 * time.Second
 	maxRetryWait   = 10 * time.Second
+
+	// Circuit breaker settings
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
 )
 
-func New(authClient *resty.Client) *resty.Client {
+// Client is the shared API client. It embeds the configured resty client
+// and keeps track of the API's rate-limit state and availability.
+type Client struct {
+	*resty.Client
+	limiter *RateLimiter
+	breaker *CircuitBreaker
+}
+
+func New(authClient *resty.Client) *Client {
 	client := resty.New()
-	
+	limiter := NewRateLimiter(maxRetryWait)
+	breaker := NewCircuitBreaker(breakerThreshold, breakerCooldown)
+
 	// Copy settings from auth client
 	client.SetBaseURL(authClient.BaseURL)
 	client.SetCookies(authClient.Cookies)
 	client.SetHeader("User-Agent", authClient.Header.Get("User-Agent"))
-	
+
 	// Configure retry and timeout settings
 	client.
 		SetTimeout(defaultTimeout).
 		SetRetryCount(maxRetries).
 		SetRetryWaitTime(retryWaitTime).
 		SetRetryMaxWaitTime(maxRetryWait).
+		SetRetryAfter(limiter.retryAfter).
 		AddRetryCondition(func(r *resty.Response, err error) bool {
-			// Retry on connection errors
+			// Retry on connection errors, but not when we are rate limited
+			// or the API is known to be down
 			if err != nil {
-				return true
+				var rateLimitErr *RateLimitError
+				var unavailableErr *APIUnavailableError
+				return !errors.As(err, &rateLimitErr) && !errors.As(err, &unavailableErr)
 			}
 			// Retry on 429 (rate limit) and 5xx errors
 			return r.StatusCode() == 429 || r.StatusCode() >= 500
 		})
 
-	// Add response middleware for better error handling
+	// Fail fast during outages; the transport sees every retry attempt. It
+	// wraps the transport of the auth client, which both clients share.
+	client.GetClient().Transport = &breakerTransport{next: authClient.GetClient().Transport, breaker: breaker}
+
+	// Hold requests back while the API asks us to wait
+	client.OnBeforeRequest(func(c *resty.Client, req *resty.Request) error {
+		return limiter.Wait(req.Context())
+	})
+
+	// Send StreamBody request bodies without reading them into memory
+	client.OnBeforeRequest(detachStreamBody)
+	client.SetPreRequestHook(attachStreamBody)
+
+	// Track rate limit headers for the limiter and retry delays
 	client.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
-		// Log rate limit headers if present
-		if remaining := resp.Header().Get("X-RateLimit-Remaining"); remaining != "" {
-			// Could log this for debugging
-		}
+		limiter.Update(resp.StatusCode(), resp.Header())
 		return nil
 	})
 
-	return client
-}
\ No newline at end of file
+	return &Client{
+		Client:  client,
+		limiter: limiter,
+		breaker: breaker,
+	}
+}
+
+// RateLimit returns the current rate-limit state reported by the API
+func (c *Client) RateLimit() RateLimitStatus {
+	return c.limiter.Status()
+}
+
+// Breaker returns the state of the circuit breaker guarding the API
+func (c *Client) Breaker() BreakerStatus {
+	return c.breaker.Status()
+}
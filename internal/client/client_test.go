@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) *Client {
+	authClient := resty.New()
+	authClient.SetBaseURL("https://api.test.com")
+	authClient.SetHeader("User-Agent", "vrc-print-upload/test")
+
+	// New sends through the transport of the auth client, so the mock ends
+	// up behind everything New installs
+	httpmock.ActivateNonDefault(authClient.GetClient())
+	t.Cleanup(httpmock.DeactivateAndReset)
+	return New(authClient)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "Seconds", value: "42", expected: 42 * time.Second, ok: true},
+		{name: "HTTP date", value: now.Add(90 * time.Second).Format(http.TimeFormat), expected: 90 * time.Second, ok: true},
+		{name: "Date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), expected: 0, ok: true},
+		{name: "Empty", value: "", ok: false},
+		{name: "Negative", value: "-5", ok: false},
+		{name: "Garbage", value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+func TestParseRateLimitReset(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	reset, ok := parseRateLimitReset("30", now)
+	require.True(t, ok)
+	assert.Equal(t, now.Add(30*time.Second), reset)
+
+	reset, ok = parseRateLimitReset("1704207900", now)
+	require.True(t, ok)
+	assert.Equal(t, int64(1704207900), reset.Unix())
+
+	_, ok = parseRateLimitReset("", now)
+	assert.False(t, ok)
+}
+
+func TestRateLimiter_Update(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	t.Run("Remaining requests", func(t *testing.T) {
+		limiter := NewRateLimiter(maxRetryWait)
+		limiter.now = func() time.Time { return now }
+
+		header := http.Header{}
+		header.Set("X-RateLimit-Limit", "60")
+		header.Set("X-RateLimit-Remaining", "12")
+		limiter.Update(http.StatusOK, header)
+
+		status := limiter.Status()
+		assert.Equal(t, 60, status.Limit)
+		assert.Equal(t, 12, status.Remaining)
+		assert.False(t, status.Limited(now))
+	})
+
+	t.Run("Retry-After on 429", func(t *testing.T) {
+		limiter := NewRateLimiter(maxRetryWait)
+		limiter.now = func() time.Time { return now }
+
+		header := http.Header{}
+		header.Set("Retry-After", "42")
+		limiter.Update(http.StatusTooManyRequests, header)
+
+		status := limiter.Status()
+		assert.True(t, status.Limited(now))
+		assert.Equal(t, 42*time.Second, status.WaitDuration(now))
+	})
+
+	t.Run("Exhausted window waits for reset", func(t *testing.T) {
+		limiter := NewRateLimiter(maxRetryWait)
+		limiter.now = func() time.Time { return now }
+
+		header := http.Header{}
+		header.Set("X-RateLimit-Remaining", "0")
+		header.Set("X-RateLimit-Reset", "15")
+		limiter.Update(http.StatusOK, header)
+
+		assert.Equal(t, 15*time.Second, limiter.Status().WaitDuration(now))
+	})
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(maxRetryWait)
+	limiter.now = func() time.Time { return now }
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+
+	header := http.Header{}
+	header.Set("Retry-After", "42")
+	limiter.Update(http.StatusTooManyRequests, header)
+
+	err := limiter.Wait(context.Background())
+	var rateLimitErr *RateLimitError
+	require.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, 42*time.Second, rateLimitErr.RetryAfter)
+}
+
+func TestClient_RetriesAfterRetryAfter(t *testing.T) {
+	c := newTestClient(t)
+
+	attempts := 0
+	httpmock.RegisterResponder("GET", "https://api.test.com/auth/user",
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				resp := httpmock.NewStringResponse(429, `{"error": "Rate limit exceeded"}`)
+				resp.Header.Set("Retry-After", "1")
+				return resp, nil
+			}
+			resp := httpmock.NewStringResponse(200, `{}`)
+			resp.Header.Set("X-RateLimit-Remaining", "9")
+			return resp, nil
+		})
+
+	start := time.Now()
+	resp, err := c.R().Get("/auth/user")
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, 2, attempts)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, 9, c.RateLimit().Remaining)
+}
+
+func TestClient_LongRetryAfterFailsFast(t *testing.T) {
+	c := newTestClient(t)
+
+	attempts := 0
+	httpmock.RegisterResponder("POST", "https://api.test.com/prints",
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			resp := httpmock.NewStringResponse(429, `{"error": "Rate limit exceeded"}`)
+			resp.Header.Set("Retry-After", "42")
+			return resp, nil
+		})
+
+	_, err := c.R().Post("/prints")
+
+	var rateLimitErr *RateLimitError
+	require.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, 1, attempts)
+	assert.True(t, c.RateLimit().Limited(time.Now()))
+
+	// Further requests are held back without reaching the API
+	_, err = c.R().Post("/prints")
+	assert.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, 30*time.Second)
+	breaker.now = func() time.Time { return now }
+
+	// Failures below the threshold keep the breaker closed
+	for i := 0; i < 2; i++ {
+		require.NoError(t, breaker.Allow())
+		breaker.Record(false)
+	}
+	assert.Equal(t, BreakerClosed, breaker.Status().State)
+
+	// A success resets the count
+	require.NoError(t, breaker.Allow())
+	breaker.Record(true)
+	assert.Equal(t, 0, breaker.Status().ConsecutiveFailures)
+
+	// Reaching the threshold opens it
+	for i := 0; i < 3; i++ {
+		require.NoError(t, breaker.Allow())
+		breaker.Record(false)
+	}
+	status := breaker.Status()
+	assert.Equal(t, BreakerOpen, status.State)
+	assert.Equal(t, now.Add(30*time.Second), status.RetryAt)
+
+	var unavailableErr *APIUnavailableError
+	require.True(t, errors.As(breaker.Allow(), &unavailableErr))
+	assert.Equal(t, 30*time.Second, unavailableErr.RetryAfter)
+
+	// After the cooldown a single trial request is let through
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, breaker.Status().State)
+	require.NoError(t, breaker.Allow())
+	now = now.Add(10 * time.Second)
+	require.True(t, errors.As(breaker.Allow(), &unavailableErr))
+	assert.Equal(t, 20*time.Second, unavailableErr.RetryAfter)
+
+	// A failed trial opens it again
+	breaker.Record(false)
+	assert.Equal(t, BreakerOpen, breaker.Status().State)
+
+	// A successful trial closes it
+	now = now.Add(31 * time.Second)
+	require.NoError(t, breaker.Allow())
+	breaker.Record(true)
+	assert.Equal(t, BreakerClosed, breaker.Status().State)
+}
+
+func TestClient_CircuitBreakerOpens(t *testing.T) {
+	c := newTestClient(t)
+	c.SetRetryCount(0)
+
+	httpmock.RegisterResponder("GET", "https://api.test.com/auth/user",
+		httpmock.NewStringResponder(503, `{"error": "Service Unavailable"}`))
+
+	for i := 0; i < breakerThreshold; i++ {
+		resp, err := c.R().Get("/auth/user")
+		require.NoError(t, err)
+		assert.Equal(t, 503, resp.StatusCode())
+	}
+	assert.Equal(t, BreakerOpen, c.Breaker().State)
+
+	// Further requests fail fast without reaching the API
+	_, err := c.R().Get("/auth/user")
+	var unavailableErr *APIUnavailableError
+	assert.True(t, errors.As(err, &unavailableErr))
+	assert.Equal(t, breakerThreshold, httpmock.GetTotalCallCount())
+}
+
+func TestClient_HalfOpenRetryAfter(t *testing.T) {
+	c := newTestClient(t)
+	c.SetRetryCount(0).SetTimeout(5 * time.Second)
+	c.breaker.cooldown = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	httpmock.RegisterResponder("GET", "https://api.test.com/auth/user",
+		func(req *http.Request) (*http.Response, error) {
+			if httpmock.GetTotalCallCount() <= breakerThreshold {
+				return httpmock.NewStringResponse(503, ""), nil
+			}
+			<-release
+			return httpmock.NewStringResponse(200, "{}"), nil
+		})
+
+	for i := 0; i < breakerThreshold; i++ {
+		_, err := c.R().Get("/auth/user")
+		require.NoError(t, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// The trial request holds the slot until it finishes or times out
+	done := make(chan error)
+	go func() {
+		_, err := c.R().Get("/auth/user")
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return httpmock.GetTotalCallCount() > breakerThreshold }, time.Second, time.Millisecond)
+
+	_, err := c.R().Get("/auth/user")
+	var unavailableErr *APIUnavailableError
+	require.True(t, errors.As(err, &unavailableErr))
+	assert.LessOrEqual(t, unavailableErr.RetryAfter, 5*time.Second)
+	assert.Greater(t, unavailableErr.RetryAfter, 4*time.Second)
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, BreakerClosed, c.Breaker().State)
+}
+
+// testStreamBody counts how often it is opened
+type testStreamBody struct {
+	data  string
+	opens int
+}
+
+func (b *testStreamBody) Open() io.ReadCloser {
+	b.opens++
+	return io.NopCloser(strings.NewReader(b.data))
+}
+
+func (b *testStreamBody) Size() int64 {
+	return int64(len(b.data))
+}
+
+func TestClient_StreamBody(t *testing.T) {
+	c := newTestClient(t)
+	c.SetRetryWaitTime(time.Millisecond)
+
+	var bodies []string
+	httpmock.RegisterResponder("POST", "https://api.test.com/prints",
+		func(req *http.Request) (*http.Response, error) {
+			data, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, fmt.Sprintf("%d:%s", req.ContentLength, data))
+			if len(bodies) == 1 {
+				return httpmock.NewStringResponse(503, ""), nil
+			}
+			return httpmock.NewStringResponse(200, "{}"), nil
+		})
+
+	// Every attempt gets a fresh copy of the body with its length
+	body := &testStreamBody{data: "form data"}
+	resp, err := c.R().SetBody(body).Post("/prints")
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, []string{"9:form data", "9:form data"}, bodies)
+	assert.Equal(t, 2, body.opens)
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Header names used by the VRChat API to report rate-limit state
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// RateLimitError is returned when the API asked us to wait longer than we
+// are willing to block a single request for.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by API: retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// RateLimitStatus is a snapshot of the most recent rate-limit information
+type RateLimitStatus struct {
+	// Limit and Remaining are -1 until the API has reported them
+	Limit     int
+	Remaining int
+	// ResetAt is when the current rate-limit window resets, if known
+	ResetAt time.Time
+	// NextAllowedAt is the earliest time the next request may be sent
+	NextAllowedAt time.Time
+}
+
+// Limited reports whether requests are currently being held back
+func (s RateLimitStatus) Limited(now time.Time) bool {
+	return s.NextAllowedAt.After(now)
+}
+
+// WaitDuration returns how long callers have to wait before the next request
+func (s RateLimitStatus) WaitDuration(now time.Time) time.Duration {
+	if !s.Limited(now) {
+		return 0
+	}
+	return s.NextAllowedAt.Sub(now)
+}
+
+// RateLimiter tracks Retry-After and X-RateLimit-* headers and holds back
+// requests until the API allows them again. It is safe for concurrent use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	status  RateLimitStatus
+	maxWait time.Duration
+	now     func() time.Time
+}
+
+// NewRateLimiter creates a limiter that blocks requests for at most maxWait
+// before failing them with a RateLimitError.
+func NewRateLimiter(maxWait time.Duration) *RateLimiter {
+	return &RateLimiter{
+		status:  RateLimitStatus{Limit: -1, Remaining: -1},
+		maxWait: maxWait,
+		now:     time.Now,
+	}
+}
+
+// Status returns the current rate-limit state
+func (l *RateLimiter) Status() RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status
+}
+
+// Update records the rate-limit headers of a response
+func (l *RateLimiter) Update(statusCode int, header http.Header) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if v, err := strconv.Atoi(header.Get(headerRateLimitLimit)); err == nil {
+		l.status.Limit = v
+	}
+	if v, err := strconv.Atoi(header.Get(headerRateLimitRemaining)); err == nil {
+		l.status.Remaining = v
+	}
+	if reset, ok := parseRateLimitReset(header.Get(headerRateLimitReset), now); ok {
+		l.status.ResetAt = reset
+	}
+
+	next := time.Time{}
+	if retryAfter, ok := parseRetryAfter(header.Get(headerRetryAfter), now); ok {
+		next = now.Add(retryAfter)
+	} else if statusCode == http.StatusTooManyRequests || l.status.Remaining == 0 {
+		// No explicit delay, so wait for the window to reset
+		next = l.status.ResetAt
+	}
+
+	if next.After(l.status.NextAllowedAt) {
+		l.status.NextAllowedAt = next
+	}
+}
+
+// Wait blocks until the next request is allowed. If that is further away
+// than the limiter's maximum wait, it returns a RateLimitError immediately.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	status := l.Status()
+	wait := status.WaitDuration(l.now())
+	if wait <= 0 {
+		return nil
+	}
+	if wait > l.maxWait {
+		return &RateLimitError{RetryAfter: wait}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryAfter is a resty.RetryAfterFunc that honors the Retry-After header.
+// Delays beyond the limiter's maximum wait stop the retries with a
+// RateLimitError instead of hammering the API.
+func (l *RateLimiter) retryAfter(c *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if resp == nil {
+		return 0, nil
+	}
+
+	wait := l.Status().WaitDuration(l.now())
+	if wait <= 0 {
+		// Use the default backoff
+		return 0, nil
+	}
+	if wait > l.maxWait {
+		return 0, &RateLimitError{RetryAfter: wait}
+	}
+	return wait, nil
+}
+
+// parseRetryAfter parses a Retry-After value given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if date.Before(now) {
+			return 0, true
+		}
+		return date.Sub(now), true
+	}
+
+	return 0, false
+}
+
+// parseRateLimitReset parses X-RateLimit-Reset, which is either a Unix
+// timestamp or a number of seconds until the window resets.
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, false
+	}
+
+	// Anything that large can only be an absolute Unix timestamp
+	if seconds > 1_000_000_000 {
+		return time.Unix(seconds, 0), true
+	}
+	return now.Add(time.Duration(seconds) * time.Second), true
+}
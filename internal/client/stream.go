@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// StreamBody is a request body that is written while it is sent instead of
+// being read into memory first. Pass it to SetBody on a request of the
+// Client; every attempt, including retries, sends a fresh copy with its
+// Content-Length. resty reads any other io.Reader body into memory to be
+// able to resend it.
+type StreamBody interface {
+	// Open starts writing a copy of the body. Closing the reader stops the
+	// writer.
+	Open() io.ReadCloser
+	// Size is the length of the body in bytes
+	Size() int64
+}
+
+type streamBodyContextKey struct{}
+
+// detachStreamBody takes a StreamBody off the request before resty reads it
+// and keeps it in the request context for attachStreamBody. It runs before
+// resty prepares the body for every attempt.
+func detachStreamBody(c *resty.Client, req *resty.Request) error {
+	body, ok := req.Body.(StreamBody)
+	if !ok {
+		return nil
+	}
+	req.Body = nil
+	req.SetContext(context.WithValue(req.Context(), streamBodyContextKey{}, body))
+	return nil
+}
+
+// attachStreamBody opens the body detached from the request for the attempt
+// about to be sent
+func attachStreamBody(c *resty.Client, req *http.Request) error {
+	body, ok := req.Context().Value(streamBodyContextKey{}).(StreamBody)
+	if !ok {
+		return nil
+	}
+	req.Body = body.Open()
+	req.ContentLength = body.Size()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return body.Open(), nil
+	}
+	return nil
+}
@@ -10,7 +10,26 @@ import (
 
 type Config struct {
 	APIBaseURL string
-	configDir  string
+	// LogLevel enables debug logging of API calls (debug, info, warn, error).
+	// Logging is off when empty.
+	LogLevel string
+	// MaxImagePixels and MaxDecodeMemoryMB cap the images that are decoded
+	// for upload. Zero uses the uploader's defaults.
+	MaxImagePixels    int64
+	MaxDecodeMemoryMB int64
+	// EmbedMetadata writes the note, world and capture details into the
+	// uploaded PNG. MetadataOmit names fields to leave out.
+	EmbedMetadata bool
+	MetadataOmit  []string
+	// VRChatLogDir is where VRChat writes output_log_*.txt, for finding the
+	// world of a screenshot. Empty uses the default location.
+	VRChatLogDir string
+	// TimestampSource selects the date of prints that aren't given one:
+	// now, capture or modified. TimeZone is an IANA zone name the date is
+	// sent in; empty keeps local time.
+	TimestampSource string
+	TimeZone        string
+	configDir       string
 }
 
 func Load(cfgFile string) (*Config, error) {
@@ -49,6 +68,15 @@ func Load(cfgFile string) (*Config, error) {
 		cfg.APIBaseURL = apiURL
 	}
 
+	cfg.LogLevel = viper.GetString("log_level")
+	cfg.MaxImagePixels = viper.GetInt64("max_image_pixels")
+	cfg.MaxDecodeMemoryMB = viper.GetInt64("max_decode_memory_mb")
+	cfg.EmbedMetadata = !viper.IsSet("embed_metadata") || viper.GetBool("embed_metadata")
+	cfg.MetadataOmit = viper.GetStringSlice("metadata_omit")
+	cfg.VRChatLogDir = viper.GetString("vrchat_log_dir")
+	cfg.TimestampSource = viper.GetString("timestamp_source")
+	cfg.TimeZone = viper.GetString("time_zone")
+
 	return cfg, nil
 }
 
@@ -56,6 +84,22 @@ func (c *Config) ConfigDir() string {
 	return c.configDir
 }
 
+func (c *Config) LogFile() string {
+	return filepath.Join(c.configDir, "logs", "vrc-print.log")
+}
+
+func (c *Config) PresetFile() string {
+	return filepath.Join(c.configDir, "presets.json")
+}
+
+func (c *Config) FrameDir() string {
+	return filepath.Join(c.configDir, "frames")
+}
+
+func (c *Config) BackupDir() string {
+	return filepath.Join(c.configDir, "backup")
+}
+
 func (c *Config) CookieFile() string {
 	// Get executable directory for portable cookie storage
 	exePath, err := os.Executable()
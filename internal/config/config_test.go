@@ -349,4 +349,143 @@ func TestLoad_ConfigFileNotFound(t *testing.T) {
 
 	// Should use default values when config file is not found
 	assert.Equal(t, "https://api.vrchat.cloud/api/1", cfg.APIBaseURL)
-}
\ No newline at end of file
+}
+func TestLoad_LogLevel(t *testing.T) {
+	// Reset viper to clean state
+	viper.Reset()
+
+	// Create config file
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test-config.yaml")
+	err := os.WriteFile(configFile, []byte(`log_level: "info"`), 0644)
+	require.NoError(t, err)
+
+	// Create temporary home directory
+	tempHome := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempHome)
+	defer os.Setenv("HOME", originalHome)
+
+	cfg, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.Equal(t, filepath.Join(tempHome, ".vrc-print", "logs", "vrc-print.log"), cfg.LogFile())
+	assert.Equal(t, filepath.Join(tempHome, ".vrc-print", "presets.json"), cfg.PresetFile())
+	assert.Equal(t, filepath.Join(tempHome, ".vrc-print", "frames"), cfg.FrameDir())
+	assert.Equal(t, filepath.Join(tempHome, ".vrc-print", "backup"), cfg.BackupDir())
+
+	// Environment variable takes precedence over the config file
+	viper.Reset()
+	os.Setenv("VRC_PRINT_LOG_LEVEL", "debug")
+	defer os.Unsetenv("VRC_PRINT_LOG_LEVEL")
+
+	cfg, err = Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.LogLevel)
+}
+
+func TestLoad_ImageLimits(t *testing.T) {
+	// Reset viper to clean state
+	viper.Reset()
+
+	// Create config file
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test-config.yaml")
+	err := os.WriteFile(configFile, []byte("max_image_pixels: 1000000\nmax_decode_memory_mb: 256"), 0644)
+	require.NoError(t, err)
+
+	// Create temporary home directory
+	tempHome := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempHome)
+	defer os.Setenv("HOME", originalHome)
+
+	cfg, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000000), cfg.MaxImagePixels)
+	assert.Equal(t, int64(256), cfg.MaxDecodeMemoryMB)
+}
+
+func TestLoad_Metadata(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedEmbed bool
+		expectedOmit  []string
+	}{
+		{
+			name:          "Default",
+			content:       "log_level: info",
+			expectedEmbed: true,
+			
+		},
+		{
+			name:          "Omitted fields",
+			content:       "metadata_omit:\n  - author\n  - sourceFile",
+			expectedEmbed: true,
+			expectedOmit:  []string{"author", "sourceFile"},
+		},
+		{
+			name:          "Disabled",
+			content:       "embed_metadata: false",
+			expectedEmbed: false,
+			
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset viper to clean state
+			viper.Reset()
+
+			configFile := filepath.Join(t.TempDir(), "test-config.yaml")
+			require.NoError(t, os.WriteFile(configFile, []byte(tt.content), 0644))
+
+			tempHome := t.TempDir()
+			originalHome := os.Getenv("HOME")
+			os.Setenv("HOME", tempHome)
+			defer os.Setenv("HOME", originalHome)
+
+			cfg, err := Load(configFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedEmbed, cfg.EmbedMetadata)
+			assert.Equal(t, tt.expectedOmit, cfg.MetadataOmit)
+		})
+	}
+}
+
+func TestLoad_VRChatLogDir(t *testing.T) {
+	// Reset viper to clean state
+	viper.Reset()
+
+	configFile := filepath.Join(t.TempDir(), "test-config.yaml")
+	logDir := "/home/user/.steam/steam/steamapps/compatdata/438100/pfx/drive_c/users/steamuser/AppData/LocalLow/VRChat/VRChat"
+	require.NoError(t, os.WriteFile(configFile, []byte("vrchat_log_dir: "+logDir), 0644))
+
+	tempHome := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempHome)
+	defer os.Setenv("HOME", originalHome)
+
+	cfg, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, logDir, cfg.VRChatLogDir)
+}
+
+func TestLoad_Timestamp(t *testing.T) {
+	// Reset viper to clean state
+	viper.Reset()
+
+	configFile := filepath.Join(t.TempDir(), "test-config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("timestamp_source: capture\ntime_zone: Asia/Tokyo"), 0644))
+
+	tempHome := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempHome)
+	defer os.Setenv("HOME", originalHome)
+
+	cfg, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "capture", cfg.TimestampSource)
+	assert.Equal(t, "Asia/Tokyo", cfg.TimeZone)
+}
@@ -0,0 +1,121 @@
+// Package frame loads the frame templates offered for prints: the built-in
+// templates and the YAML files in the user's frame directory
+package frame
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yoshiken/vrc-print-upload/internal/upload"
+)
+
+// ErrNotFound is returned for templates that don't exist
+var ErrNotFound = errors.New("frame template not found")
+
+//go:embed templates/*.yaml
+var builtin embed.FS
+
+// Library lists the built-in templates and the templates in a directory.
+// A template in the directory replaces a built-in one with the same name.
+type Library struct {
+	dir    string
+	logger *slog.Logger
+}
+
+// NewLibrary returns a library reading user templates from dir, which
+// doesn't have to exist. User templates that can't be loaded are skipped
+// and reported to logger, if set.
+func NewLibrary(dir string, logger *slog.Logger) *Library {
+	return &Library{dir: dir, logger: logger}
+}
+
+// List returns the templates sorted by name. Background images of user
+// templates are resolved relative to the template file.
+func (l *Library) List() ([]upload.FrameTemplate, error) {
+	byName := map[string]upload.FrameTemplate{}
+
+	if errs := loadTemplates(builtin, "templates", "", byName); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// A broken user template shouldn't hide the others
+	if l.dir != "" {
+		var errs []error
+		if _, err := os.Stat(l.dir); err == nil {
+			errs = loadTemplates(os.DirFS(l.dir), ".", l.dir, byName)
+		} else if !os.IsNotExist(err) {
+			errs = []error{fmt.Errorf("failed to read frame directory: %w", err)}
+		}
+		if l.logger != nil {
+			for _, err := range errs {
+				l.logger.Warn("skipping frame template", "error", err)
+			}
+		}
+	}
+
+	templates := make([]upload.FrameTemplate, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Get returns the template with the given name
+func (l *Library) Get(name string) (*upload.FrameTemplate, error) {
+	templates, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// loadTemplates parses the .yaml and .yml files in dir of fsys into byName,
+// resolving relative background images against base. Files that can't be
+// loaded are skipped; it returns an error for each of them.
+func loadTemplates(fsys fs.FS, dir, base string, byName map[string]upload.FrameTemplate) []error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read frame directory: %w", err)}
+	}
+
+	var errs []error
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read frame template %s: %w", entry.Name(), err))
+			continue
+		}
+		t, err := upload.ParseFrameTemplate(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("frame template %s: %w", entry.Name(), err))
+			continue
+		}
+		if t.BackgroundImage != "" && !filepath.IsAbs(t.BackgroundImage) {
+			t.BackgroundImage = filepath.Join(base, t.BackgroundImage)
+		}
+		byName[t.Name] = *t
+	}
+	return errs
+}
@@ -0,0 +1,85 @@
+package frame
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoshiken/vrc-print-upload/internal/upload"
+)
+
+func TestLibrary_Builtin(t *testing.T) {
+	// A missing directory only lists the built-in templates
+	templates, err := NewLibrary(filepath.Join(t.TempDir(), "frames"), nil).List()
+	require.NoError(t, err)
+
+	var names []string
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+	}
+	assert.Equal(t, []string{"シンプル", "ポストカード", "ポラロイド"}, names)
+
+	polaroid, err := NewLibrary("", nil).Get("ポラロイド")
+	require.NoError(t, err)
+	require.NotNil(t, polaroid.Caption)
+	assert.Equal(t, 0.2, polaroid.Border.Bottom)
+}
+
+func TestLibrary_UserTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"paper.yaml":    "name: Paper\nborder: {bottom: 0.1}\nbackgroundImage: textures/paper.png\n",
+		"override.yml":  "name: シンプル\ncolor: \"#ffffff\"\n",
+		"notes.txt":     "not a template",
+		"absolute.yaml": "name: Absolute\nbackgroundImage: " + filepath.Join(dir, "bg.png") + "\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	library := NewLibrary(dir, nil)
+	templates, err := library.List()
+	require.NoError(t, err)
+	assert.Len(t, templates, 5)
+
+	paper, err := library.Get("Paper")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "textures", "paper.png"), paper.BackgroundImage)
+
+	absolute, err := library.Get("Absolute")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "bg.png"), absolute.BackgroundImage)
+
+	// User templates replace built-in ones with the same name
+	simple, err := library.Get("シンプル")
+	require.NoError(t, err)
+	assert.Equal(t, upload.FrameTemplate{Name: "シンプル", Color: "#ffffff"}, *simple)
+
+	_, err = library.Get("Missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLibrary_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("name: Broken\nborder: {top: 2}\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "malformed.yaml"), []byte("name: [\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "paper.yaml"), []byte("name: Paper\n"), 0600))
+
+	// Broken templates are skipped and logged; the others are still listed
+	var logs bytes.Buffer
+	library := NewLibrary(dir, slog.New(slog.NewTextHandler(&logs, nil)))
+	templates, err := library.List()
+	require.NoError(t, err)
+	assert.Len(t, templates, 4)
+	assert.Contains(t, logs.String(), "frame template broken.yaml: invalid frame border")
+	assert.Contains(t, logs.String(), "frame template malformed.yaml")
+
+	_, err = library.Get("ポラロイド")
+	assert.NoError(t, err)
+	_, err = library.Get("Broken")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// maxLoggedBody caps how much of a response body is written at debug level
+const maxLoggedBody = 2048
+
+// Instrument logs every API call made through c: method, path, status,
+// latency and retry count at info level, plus redacted headers and JSON
+// bodies at debug level.
+func Instrument(c *resty.Client, logger *slog.Logger) {
+	c.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		req := resp.Request
+		ctx := req.Context()
+
+		level := slog.LevelInfo
+		if resp.IsError() {
+			level = slog.LevelWarn
+		}
+
+		logger.Log(ctx, level, "api request",
+			slog.String("method", req.Method),
+			slog.String("path", requestPath(req)),
+			slog.Int("status", resp.StatusCode()),
+			slog.Duration("latency", resp.Time()),
+			slog.Int("retries", retries(req)),
+		)
+
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			logDebug(ctx, logger, resp)
+		}
+		return nil
+	})
+
+	c.OnError(func(req *resty.Request, err error) {
+		logger.Error("api request failed",
+			slog.String("method", req.Method),
+			slog.String("path", requestPath(req)),
+			slog.Int("retries", retries(req)),
+			slog.String("error", err.Error()),
+		)
+	})
+}
+
+func logDebug(ctx context.Context, logger *slog.Logger, resp *resty.Response) {
+	req := resp.Request
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", requestPath(req)),
+		slog.Any("request_headers", RedactHeaders(req.Header)),
+		slog.Any("response_headers", RedactHeaders(resp.Header())),
+	}
+
+	if body := requestBody(req); body != "" {
+		attrs = append(attrs, slog.String("request_body", body))
+	}
+
+	if strings.Contains(resp.Header().Get("Content-Type"), "json") {
+		body := RedactJSON(resp.Body())
+		if len(body) > maxLoggedBody {
+			body = body[:maxLoggedBody] + "..."
+		}
+		attrs = append(attrs, slog.String("response_body", body))
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "api request detail", attrs...)
+}
+
+// requestBody returns the redacted request body for structured bodies.
+// Raw and multipart bodies such as image uploads are skipped.
+func requestBody(req *resty.Request) string {
+	switch req.Body.(type) {
+	case nil, []byte, string, io.Reader:
+		return ""
+	}
+
+	data, err := json.Marshal(req.Body)
+	if err != nil {
+		return ""
+	}
+	return RedactJSON(data)
+}
+
+func requestPath(req *resty.Request) string {
+	if req.RawRequest != nil && req.RawRequest.URL != nil {
+		return req.RawRequest.URL.Path
+	}
+	return req.URL
+}
+
+func retries(req *resty.Request) int {
+	if req.Attempt > 1 {
+		return req.Attempt - 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/yoshiken/vrc-print-upload/internal/config"
+)
+
+const (
+	maxLogFileSize = 5 * 1024 * 1024 // 5MB
+	maxLogBackups  = 3
+)
+
+// New creates the logger configured by cfg. Logging is opt-in: when no log
+// level is set, the returned logger discards everything. The returned closer
+// must be closed on shutdown.
+func New(cfg *config.Config) (*slog.Logger, io.Closer, error) {
+	level, enabled, err := ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !enabled {
+		return Discard(), nopCloser{}, nil
+	}
+
+	file, err := NewRotatingFile(cfg.LogFile(), maxLogFileSize, maxLogBackups)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	handler := slog.NewJSONHandler(file, &slog.HandlerOptions{Level: level})
+	return slog.New(handler), file, nil
+}
+
+// ParseLevel parses a log level name. Empty and "off" disable logging.
+func ParseLevel(name string) (slog.Level, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "off", "none":
+		return 0, false, nil
+	case "debug":
+		return slog.LevelDebug, true, nil
+	case "info":
+		return slog.LevelInfo, true, nil
+	case "warn", "warning":
+		return slog.LevelWarn, true, nil
+	case "error":
+		return slog.LevelError, true, nil
+	default:
+		return 0, false, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
+// Discard returns a logger that drops all records
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoshiken/vrc-print-upload/internal/config"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    slog.Level
+		enabled     bool
+		expectError bool
+	}{
+		{name: "", enabled: false},
+		{name: "off", enabled: false},
+		{name: "debug", expected: slog.LevelDebug, enabled: true},
+		{name: "INFO", expected: slog.LevelInfo, enabled: true},
+		{name: "warn", expected: slog.LevelWarn, enabled: true},
+		{name: "error", expected: slog.LevelError, enabled: true},
+		{name: "verbose", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, enabled, err := ParseLevel(tt.name)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.enabled, enabled)
+			assert.Equal(t, tt.expected, level)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Disabled by default", func(t *testing.T) {
+		cfg := &config.Config{}
+		logger, closer, err := New(cfg)
+		require.NoError(t, err)
+		defer closer.Close()
+
+		assert.False(t, logger.Enabled(context.Background(), slog.LevelError))
+	})
+
+	t.Run("Writes to log file", func(t *testing.T) {
+		tempHome := t.TempDir()
+		originalHome := os.Getenv("HOME")
+		os.Setenv("HOME", tempHome)
+		defer os.Setenv("HOME", originalHome)
+
+		cfg, err := config.Load("")
+		require.NoError(t, err)
+		cfg.LogLevel = "info"
+
+		logger, closer, err := New(cfg)
+		require.NoError(t, err)
+
+		logger.Info("hello")
+		require.NoError(t, closer.Close())
+
+		data, err := os.ReadFile(cfg.LogFile())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"msg":"hello"`)
+	})
+}
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Basic dXNlcjpwYXNz")
+	h.Set("Cookie", "auth=secret")
+	h.Set("Set-Cookie", "auth=secret; Path=/")
+	h.Set("User-Agent", "vrc-print-upload/1.0")
+
+	out := RedactHeaders(h)
+	assert.Equal(t, redacted, out["Authorization"])
+	assert.Equal(t, redacted, out["Cookie"])
+	assert.Equal(t, redacted, out["Set-Cookie"])
+	assert.Equal(t, "vrc-print-upload/1.0", out["User-Agent"])
+}
+
+func TestRedactJSON(t *testing.T) {
+	out := RedactJSON([]byte(`{"code":"123456","user":{"password":"hunter2","displayName":"Test"}}`))
+	assert.NotContains(t, out, "123456")
+	assert.NotContains(t, out, "hunter2")
+	assert.Contains(t, out, "Test")
+
+	assert.Equal(t, redacted, RedactJSON([]byte("not json")))
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "test.log")
+
+	file, err := NewRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first---\n", "second--\n", "third---\n", "fourth--\n"} {
+		_, err := file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, file.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth--\n", string(current))
+
+	backup, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "third---\n", string(backup))
+
+	backup, err = os.ReadFile(path + ".2")
+	require.NoError(t, err)
+	assert.Equal(t, "second--\n", string(backup))
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInstrument(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := resty.New()
+	client.SetBaseURL("https://api.test.com")
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	Instrument(client, logger)
+
+	httpmock.RegisterResponder("POST", "https://api.test.com/auth/twofactorauth/totp/verify",
+		func(req *http.Request) (*http.Response, error) {
+			resp, _ := httpmock.NewJsonResponse(200, map[string]interface{}{"verified": true})
+			resp.Header.Set("Set-Cookie", "twoFactorAuth=secret-cookie; Path=/")
+			return resp, nil
+		})
+
+	_, err := client.R().
+		SetHeader("Authorization", "Basic secret-credentials").
+		SetBody(map[string]string{"code": "654321"}).
+		Post("/auth/twofactorauth/totp/verify")
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.NotContains(t, output, "654321")
+	assert.NotContains(t, output, "secret-credentials")
+	assert.NotContains(t, output, "secret-cookie")
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "api request", entry["msg"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/auth/twofactorauth/totp/verify", entry["path"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(0), entry["retries"])
+	assert.Contains(t, entry, "latency")
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are never written to the log
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// sensitiveFields are JSON keys whose values are never written to the log.
+// "code" covers TOTP and recovery codes sent to the 2FA endpoints.
+var sensitiveFields = map[string]bool{
+	"password":      true,
+	"code":          true,
+	"token":         true,
+	"auth":          true,
+	"authorization": true,
+	"cookie":        true,
+	"twofactorauth": true,
+}
+
+// RedactHeaders returns a copy of h suitable for logging, with credentials
+// and cookies replaced.
+func RedactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if sensitiveHeaders[strings.ToLower(name)] {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// RedactJSON returns body with the values of sensitive fields replaced. Bodies
+// that are not JSON are not logged at all, since they may be image data or
+// contain credentials in an unknown shape.
+func RedactJSON(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return redacted
+	}
+
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return redacted
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, field := range val {
+			if sensitiveFields[strings.ToLower(key)] {
+				val[key] = redacted
+				continue
+			}
+			val[key] = redactValue(field)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
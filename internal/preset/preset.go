@@ -0,0 +1,191 @@
+// Package preset stores named sets of image processing options
+package preset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/yoshiken/vrc-print-upload/internal/upload"
+)
+
+// ErrNotFound is returned for presets that don't exist
+var ErrNotFound = errors.New("preset not found")
+
+// Preset is a named set of processing options. The fields use the same
+// notation as the GUI upload request, so that a preset can fill the form.
+type Preset struct {
+	Name string `json:"name"`
+	// ResizeMode is one of "stretch", "fit", "fill" or "original"
+	ResizeMode string `json:"resizeMode"`
+	// PadColor is the letterbox color for "fit" in #RRGGBB notation
+	PadColor string `json:"padColor,omitempty"`
+	// CropAnchor is one of "center", "focal" or "smart"
+	CropAnchor     string `json:"cropAnchor,omitempty"`
+	Rotate         int    `json:"rotate"`
+	FlipHorizontal bool   `json:"flipHorizontal"`
+	FlipVertical   bool   `json:"flipVertical"`
+	PreserveAlpha  bool   `json:"preserveAlpha"`
+	// BackgroundColor fills transparent areas in #RRGGBB notation
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	Shrink          bool   `json:"shrink"`
+	Quantize        bool   `json:"quantize"`
+	// Filters are the processing steps, in order
+	Filters []upload.FilterSpec `json:"filters"`
+	// Frame is the name of a frame template; no frame if empty
+	Frame string `json:"frame,omitempty"`
+	// Watermarks are stamped in order after the frame
+	Watermarks []upload.Watermark `json:"watermarks"`
+}
+
+// Validate checks that every option of the preset is valid
+func (p Preset) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("preset name is required")
+	}
+	if _, err := upload.ParseResizeMode(p.ResizeMode); err != nil {
+		return err
+	}
+	for _, c := range []string{p.PadColor, p.BackgroundColor} {
+		if c == "" {
+			continue
+		}
+		if _, err := upload.ParseHexColor(c); err != nil {
+			return err
+		}
+	}
+	if _, err := upload.ParseCropAnchor(p.CropAnchor); err != nil {
+		return err
+	}
+	if !upload.Rotation(p.Rotate).Valid() {
+		return fmt.Errorf("invalid rotation: %d (must be 0, 90, 180 or 270)", p.Rotate)
+	}
+	if _, err := upload.ParseFilters(p.Filters); err != nil {
+		return err
+	}
+	for i, w := range p.Watermarks {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("watermark %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Store keeps presets in a JSON file
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewStore returns a store backed by the file at path, which is created on
+// the first save
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// List returns the saved presets in the order they were first saved
+func (s *Store) List() ([]Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+// Get returns the preset with the given name
+func (s *Store) Get(name string) (*Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presets, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range presets {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Save validates p and stores it, replacing a preset with the same name
+func (s *Store) Save(p Preset) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range presets {
+		if presets[i].Name == p.Name {
+			presets[i] = p
+			replaced = true
+		}
+	}
+	if !replaced {
+		presets = append(presets, p)
+	}
+
+	return s.save(presets)
+}
+
+// Delete removes the preset with the given name
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for i, p := range presets {
+		if p.Name == name {
+			return s.save(append(presets[:i], presets[i+1:]...))
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+func (s *Store) load() ([]Preset, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Preset{}, nil
+		}
+		return nil, fmt.Errorf("failed to open presets: %w", err)
+	}
+	defer file.Close()
+
+	var presets []Preset
+	if err := json.NewDecoder(file).Decode(&presets); err != nil {
+		return nil, fmt.Errorf("failed to read presets: %w", err)
+	}
+	return presets, nil
+}
+
+func (s *Store) save(presets []Preset) error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create presets file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(presets); err != nil {
+		return fmt.Errorf("failed to write presets: %w", err)
+	}
+	return nil
+}
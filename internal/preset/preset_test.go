@@ -0,0 +1,144 @@
+package preset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoshiken/vrc-print-upload/internal/upload"
+)
+
+func TestPreset_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		preset      Preset
+		expectError string
+	}{
+		{
+			name:   "Minimal",
+			preset: Preset{Name: "Default"},
+		},
+		{
+			name: "Everything",
+			preset: Preset{
+				Name:            "Warm",
+				ResizeMode:      "fill",
+				PadColor:        "#102030",
+				CropAnchor:      "smart",
+				Rotate:          90,
+				BackgroundColor: "#ffffff",
+				Filters: []upload.FilterSpec{
+					{Type: upload.FilterSaturation, Amount: 20},
+					{Type: upload.FilterSharpen, Amount: 1},
+				},
+				Watermarks: []upload.Watermark{
+					{Text: "yoshiken", Position: upload.PositionBottomLeft, Opacity: 0.7},
+					{ImagePath: "/home/user/logo.png", Scale: 0.1},
+				},
+			},
+		},
+		{
+			name:        "Blank name",
+			preset:      Preset{Name: "  "},
+			expectError: "preset name is required",
+		},
+		{
+			name:        "Invalid resize mode",
+			preset:      Preset{Name: "x", ResizeMode: "zoom"},
+			expectError: "unknown resize mode",
+		},
+		{
+			name:        "Invalid background color",
+			preset:      Preset{Name: "x", BackgroundColor: "white"},
+			expectError: "invalid color",
+		},
+		{
+			name:        "Invalid rotation",
+			preset:      Preset{Name: "x", Rotate: 45},
+			expectError: "invalid rotation",
+		},
+		{
+			name:        "Invalid filter",
+			preset:      Preset{Name: "x", Filters: []upload.FilterSpec{{Type: upload.FilterBlur}}},
+			expectError: "filter 1: invalid blur sigma",
+		},
+		{
+			name:        "Invalid watermark",
+			preset:      Preset{Name: "x", Watermarks: []upload.Watermark{{Text: "a", Position: "middle"}}},
+			expectError: "watermark 1: unknown watermark position",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.preset.Validate()
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "presets.json")
+	store := NewStore(path)
+
+	// A missing file is an empty list
+	presets, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, presets)
+
+	soft := Preset{Name: "Soft", ResizeMode: "fit", Filters: []upload.FilterSpec{{Type: upload.FilterBlur, Amount: 1}}}
+	vivid := Preset{Name: "Vivid", ResizeMode: "fill", Filters: []upload.FilterSpec{{Type: upload.FilterSaturation, Amount: 40}}}
+	require.NoError(t, store.Save(soft))
+	require.NoError(t, store.Save(vivid))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Saving under an existing name replaces the preset in place
+	soft.ResizeMode = "stretch"
+	soft.Name = " Soft "
+	require.NoError(t, store.Save(soft))
+
+	presets, err = NewStore(path).List()
+	require.NoError(t, err)
+	require.Len(t, presets, 2)
+	assert.Equal(t, "Soft", presets[0].Name)
+	assert.Equal(t, "stretch", presets[0].ResizeMode)
+	assert.Equal(t, vivid, presets[1])
+
+	got, err := store.Get("Vivid")
+	require.NoError(t, err)
+	assert.Equal(t, vivid, *got)
+
+	require.NoError(t, store.Delete("Soft"))
+	presets, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []Preset{vivid}, presets)
+
+	assert.ErrorIs(t, store.Delete("Soft"), ErrNotFound)
+	_, err = store.Get("Soft")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Invalid presets aren't saved
+	assert.Error(t, store.Save(Preset{Name: "Broken", Rotate: 45}))
+	presets, err = store.List()
+	require.NoError(t, err)
+	assert.Len(t, presets, 1)
+}
+
+func TestStore_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "presets.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+
+	_, err := NewStore(path).List()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read presets")
+}
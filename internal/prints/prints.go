@@ -0,0 +1,225 @@
+// Package prints manages the prints a user has uploaded: listing them page
+// by page, fetching one and deleting it
+package prints
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrNotFound is returned for prints that don't exist
+var ErrNotFound = errors.New("print not found")
+
+const (
+	// MaxPageSize is the most prints the API returns per page
+	MaxPageSize = 100
+	// DefaultPageSize is used when no page size is given
+	DefaultPageSize = 60
+)
+
+// Print is a print as returned by the API
+type Print struct {
+	ID         string `json:"id"`
+	AuthorID   string `json:"authorId"`
+	AuthorName string `json:"authorName"`
+	OwnerID    string `json:"ownerId"`
+	Note       string `json:"note"`
+	WorldID    string `json:"worldId"`
+	WorldName  string `json:"worldName"`
+	// Timestamp is the date shown on the print; CreatedAt is when it was
+	// uploaded
+	Timestamp time.Time `json:"timestamp"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     Files     `json:"files"`
+}
+
+// Files locates the image of a print
+type Files struct {
+	FileID string `json:"fileId"`
+	// Image is the URL of the full-size image
+	Image string `json:"image"`
+}
+
+// ListOptions selects a page of prints. The zero value is the first page of
+// DefaultPageSize prints.
+type ListOptions struct {
+	Offset int
+	Limit  int
+}
+
+// PageSize is the number of prints requested: Limit, or DefaultPageSize if
+// it is unset, at most MaxPageSize
+func (o ListOptions) PageSize() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultPageSize
+	case o.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return o.Limit
+	}
+}
+
+// Service reads and deletes prints through the API client
+type Service struct {
+	client *resty.Client
+}
+
+func New(client *resty.Client) *Service {
+	return &Service{
+		client: client,
+	}
+}
+
+// List returns a page of the prints of a user, newest first. A page shorter
+// than the limit is the last one.
+func (s *Service) List(ctx context.Context, userID string, opts ListOptions) ([]Print, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	if opts.Offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d", opts.Offset)
+	}
+
+	var page []Print
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParam("n", fmt.Sprint(opts.PageSize())).
+		SetQueryParam("offset", fmt.Sprint(opts.Offset)).
+		SetResult(&page).
+		Get("/prints/user/" + url.PathEscape(userID))
+	if err != nil {
+		return nil, fmt.Errorf("list prints request failed: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("list prints failed with status %d: %s", resp.StatusCode(), resp.String())
+	}
+	return page, nil
+}
+
+// Get returns the print with the given ID
+func (s *Service) Get(ctx context.Context, printID string) (*Print, error) {
+	if printID == "" {
+		return nil, errors.New("print ID is required")
+	}
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetResult(&Print{}).
+		Get(printPath(printID))
+	if err != nil {
+		return nil, fmt.Errorf("get print request failed: %w", err)
+	}
+	if err := checkStatus(resp, printID, "get print"); err != nil {
+		return nil, err
+	}
+	return resp.Result().(*Print), nil
+}
+
+// Delete deletes the print with the given ID
+func (s *Service) Delete(ctx context.Context, printID string) error {
+	if printID == "" {
+		return errors.New("print ID is required")
+	}
+	resp, err := s.client.R().
+		SetContext(ctx).
+		Delete(printPath(printID))
+	if err != nil {
+		return fmt.Errorf("delete print request failed: %w", err)
+	}
+	return checkStatus(resp, printID, "delete print")
+}
+
+// DownloadImage writes the full-size image of p to w and returns the number
+// of bytes written
+func (s *Service) DownloadImage(ctx context.Context, p Print, w io.Writer) (int64, error) {
+	if p.Files.Image == "" {
+		return 0, fmt.Errorf("print %s has no image", p.ID)
+	}
+	// The client sends the session cookies to any host
+	if !s.onAPIHost(p.Files.Image) {
+		return 0, fmt.Errorf("image of print %s is not on the API host: %s", p.ID, p.Files.Image)
+	}
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		Get(p.Files.Image)
+	if err != nil {
+		return 0, fmt.Errorf("download image request failed: %w", err)
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	if resp.StatusCode() != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(body, 1024))
+		return 0, fmt.Errorf("download image failed with status %d: %s", resp.StatusCode(), message)
+	}
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, fmt.Errorf("failed to download image: %w", err)
+	}
+	return n, nil
+}
+
+// onAPIHost reports whether rawURL has the scheme and host of the API base
+// URL. Relative URLs are resolved against the base URL.
+func (s *Service) onAPIHost(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return true
+	}
+	base, err := url.Parse(s.client.BaseURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, base.Scheme) && strings.EqualFold(u.Host, base.Host)
+}
+
+// Thumbnail downloads the image of p and returns it as a JPEG scaled to fit
+// maxSize
+func (s *Service) Thumbnail(ctx context.Context, p Print, maxSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := s.DownloadImage(ctx, p, &buf); err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Decode(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	thumb := imaging.Fit(img, maxSize, maxSize, imaging.Linear)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func printPath(printID string) string {
+	return "/prints/" + url.PathEscape(printID)
+}
+
+func checkStatus(resp *resty.Response, printID, action string) error {
+	switch resp.StatusCode() {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, printID)
+	default:
+		return fmt.Errorf("%s failed with status %d: %s", action, resp.StatusCode(), resp.String())
+	}
+}
@@ -0,0 +1,230 @@
+package prints
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "https://api.vrchat.cloud/api/1"
+
+const printJSON = `{
+	"id": "prnt_12345",
+	"authorId": "usr_12345",
+	"authorName": "TestUser",
+	"ownerId": "usr_12345",
+	"note": "メモ",
+	"worldId": "wrld_12345",
+	"worldName": "Test World",
+	"timestamp": "2024-03-15T12:30:05.000Z",
+	"createdAt": "2024-03-15T12:31:00.000Z",
+	"files": {
+		"fileId": "file_12345",
+		"image": "https://api.vrchat.cloud/api/1/file/file_12345/1/file"
+	}
+}`
+
+func newTestService(t *testing.T) *Service {
+	client := resty.New()
+	client.SetBaseURL(baseURL)
+	httpmock.ActivateNonDefault(client.GetClient())
+	t.Cleanup(httpmock.DeactivateAndReset)
+	return New(client)
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           ListOptions
+		expectedN      string
+		expectedOffset string
+	}{
+		{
+			name:           "Default page",
+			expectedN:      "60",
+			expectedOffset: "0",
+		},
+		{
+			name:           "Second page",
+			opts:           ListOptions{Offset: 20, Limit: 20},
+			expectedN:      "20",
+			expectedOffset: "20",
+		},
+		{
+			name:           "Limit above maximum",
+			opts:           ListOptions{Limit: 500},
+			expectedN:      "100",
+			expectedOffset: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t)
+			httpmock.RegisterResponder("GET", baseURL+"/prints/user/usr_12345",
+				func(req *http.Request) (*http.Response, error) {
+					assert.Equal(t, tt.expectedN, req.URL.Query().Get("n"))
+					assert.Equal(t, tt.expectedOffset, req.URL.Query().Get("offset"))
+					resp := httpmock.NewStringResponse(200, "["+printJSON+"]")
+					resp.Header.Set("Content-Type", "application/json")
+					return resp, nil
+				})
+
+			page, err := service.List(context.Background(), "usr_12345", tt.opts)
+			require.NoError(t, err)
+			require.Len(t, page, 1)
+
+			got := page[0]
+			assert.Equal(t, "prnt_12345", got.ID)
+			assert.Equal(t, "usr_12345", got.AuthorID)
+			assert.Equal(t, "TestUser", got.AuthorName)
+			assert.Equal(t, "usr_12345", got.OwnerID)
+			assert.Equal(t, "メモ", got.Note)
+			assert.Equal(t, "wrld_12345", got.WorldID)
+			assert.Equal(t, "Test World", got.WorldName)
+			assert.True(t, time.Date(2024, 3, 15, 12, 30, 5, 0, time.UTC).Equal(got.Timestamp))
+			assert.True(t, time.Date(2024, 3, 15, 12, 31, 0, 0, time.UTC).Equal(got.CreatedAt))
+			assert.Equal(t, "file_12345", got.Files.FileID)
+			assert.Equal(t, "https://api.vrchat.cloud/api/1/file/file_12345/1/file", got.Files.Image)
+		})
+	}
+}
+
+func TestList_Errors(t *testing.T) {
+	service := newTestService(t)
+	httpmock.RegisterResponder("GET", baseURL+"/prints/user/usr_12345",
+		httpmock.NewStringResponder(401, `{"error":{"message":"Missing Credentials"}}`))
+
+	_, err := service.List(context.Background(), "", ListOptions{})
+	assert.Error(t, err)
+
+	_, err = service.List(context.Background(), "usr_12345", ListOptions{Offset: -1})
+	assert.Error(t, err)
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+
+	_, err = service.List(context.Background(), "usr_12345", ListOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "list prints failed with status 401")
+}
+
+func TestGet(t *testing.T) {
+	service := newTestService(t)
+	httpmock.RegisterResponder("GET", baseURL+"/prints/prnt_12345", func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, printJSON)
+		resp.Header.Set("Content-Type", "application/json")
+		return resp, nil
+	})
+	httpmock.RegisterResponder("GET", baseURL+"/prints/prnt_missing",
+		httpmock.NewStringResponder(404, `{"error":{"message":"Print not found"}}`))
+
+	got, err := service.Get(context.Background(), "prnt_12345")
+	require.NoError(t, err)
+	assert.Equal(t, "prnt_12345", got.ID)
+	assert.Equal(t, "file_12345", got.Files.FileID)
+
+	_, err = service.Get(context.Background(), "prnt_missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = service.Get(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		expectError error
+	}{
+		{name: "Deleted", status: 200},
+		{name: "Not found", status: 404, expectError: ErrNotFound},
+		{name: "Forbidden", status: 403, expectError: errors.New("delete print failed with status 403")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t)
+			httpmock.RegisterResponder("DELETE", baseURL+"/prints/prnt_12345",
+				httpmock.NewStringResponder(tt.status, `{}`))
+
+			err := service.Delete(context.Background(), "prnt_12345")
+			switch {
+			case tt.expectError == nil:
+				assert.NoError(t, err)
+			case tt.expectError == ErrNotFound:
+				assert.True(t, errors.Is(err, ErrNotFound))
+			default:
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError.Error())
+			}
+			assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		})
+	}
+}
+
+func TestDownloadImage(t *testing.T) {
+	service := newTestService(t)
+	imageURL := baseURL + "/file/file_12345/1/file"
+	httpmock.RegisterResponder("GET", imageURL, httpmock.NewBytesResponder(200, []byte("png data")))
+	httpmock.RegisterResponder("GET", baseURL+"/file/file_missing/1/file",
+		httpmock.NewStringResponder(404, "not found"))
+
+	var buf bytes.Buffer
+	n, err := service.DownloadImage(context.Background(), Print{ID: "prnt_12345", Files: Files{Image: imageURL}}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), n)
+	assert.Equal(t, "png data", buf.String())
+
+	_, err = service.DownloadImage(context.Background(), Print{ID: "prnt_12345", Files: Files{Image: baseURL + "/file/file_missing/1/file"}}, &buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download image failed with status 404")
+
+	_, err = service.DownloadImage(context.Background(), Print{ID: "prnt_12345"}, &buf)
+	assert.Error(t, err)
+
+	// The session cookies aren't sent to other hosts
+	for _, imageURL := range []string{"https://evil.example.com/file/file_12345/1/file", "http://api.vrchat.cloud/api/1/file/file_12345/1/file"} {
+		_, err = service.DownloadImage(context.Background(), Print{ID: "prnt_12345", Files: Files{Image: imageURL}}, &buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not on the API host")
+	}
+	assert.Equal(t, 2, httpmock.GetTotalCallCount())
+}
+
+func TestThumbnail(t *testing.T) {
+	service := newTestService(t)
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := 0; y < 1080; y++ {
+		for x := 0; x < 1920; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var data bytes.Buffer
+	require.NoError(t, png.Encode(&data, img))
+
+	imageURL := baseURL + "/file/file_12345/1/file"
+	httpmock.RegisterResponder("GET", imageURL, httpmock.NewBytesResponder(200, data.Bytes()))
+	httpmock.RegisterResponder("GET", baseURL+"/file/file_broken/1/file", httpmock.NewStringResponder(200, "not an image"))
+
+	thumb, err := service.Thumbnail(context.Background(), Print{ID: "prnt_12345", Files: Files{Image: imageURL}}, 400)
+	require.NoError(t, err)
+	decoded, err := jpeg.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, 400, decoded.Bounds().Dx())
+	assert.Equal(t, 225, decoded.Bounds().Dy())
+
+	_, err = service.Thumbnail(context.Background(), Print{ID: "prnt_12345", Files: Files{Image: baseURL + "/file/file_broken/1/file"}}, 400)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode image")
+}
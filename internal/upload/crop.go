@@ -0,0 +1,240 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// CropAnchor selects which part of the image ResizeFill keeps
+type CropAnchor string
+
+const (
+	// CropCenter keeps the center of the image. This is the default.
+	CropCenter CropAnchor = "center"
+	// CropFocal centers the crop on Options.FocalPoint as far as the image
+	// bounds allow
+	CropFocal CropAnchor = "focal"
+	// CropSmart centers the crop on the region with the most detail
+	CropSmart CropAnchor = "smart"
+)
+
+// smartCropAnalysisSize is the longest side of the downscaled copy that
+// smart cropping analyzes
+const smartCropAnalysisSize = 256
+
+// FocalPoint is a point in normalized image coordinates: (0, 0) is the
+// top-left corner and (1, 1) the bottom-right corner
+type FocalPoint struct {
+	X float64
+	Y float64
+}
+
+// CenterFocalPoint is the center of the image
+var CenterFocalPoint = FocalPoint{X: 0.5, Y: 0.5}
+
+// Valid reports whether both coordinates are within [0, 1]
+func (p FocalPoint) Valid() bool {
+	return p.X >= 0 && p.X <= 1 && p.Y >= 0 && p.Y <= 1
+}
+
+// ParseCropAnchor parses a crop anchor name. An empty name selects
+// CropCenter.
+func ParseCropAnchor(name string) (CropAnchor, error) {
+	switch anchor := CropAnchor(strings.ToLower(strings.TrimSpace(name))); anchor {
+	case "":
+		return CropCenter, nil
+	case CropCenter, CropFocal, CropSmart:
+		return anchor, nil
+	default:
+		return "", fmt.Errorf("unknown crop anchor: %s", name)
+	}
+}
+
+// CropPreview describes which part of an image ResizeFill keeps
+type CropPreview struct {
+	// Width and Height are the dimensions of the source image
+	Width  int
+	Height int
+	// Crop is the region that is kept, in source image pixels
+	Crop image.Rectangle
+	// FocalPoint is the point the crop is centered on. For CropSmart this
+	// is the detected point of interest.
+	FocalPoint FocalPoint
+	// Thumbnail is a JPEG of the whole source image, scaled to fit maxSize
+	Thumbnail []byte
+}
+
+// PreviewCrop decodes and processes the image at opts.ImagePath and reports
+// how it would be cropped in ResizeFill mode with the crop anchor in opts,
+// along with a thumbnail for displaying it.
+func PreviewCrop(ctx context.Context, opts Options, maxSize int) (*CropPreview, error) {
+	if err := validateProcessing(opts); err != nil {
+		return nil, err
+	}
+
+	img, err := loadImage(ctx, opts.ImagePath, opts.Limits, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFilterSize(opts, img.Bounds().Dx(), img.Bounds().Dy()); err != nil {
+		return nil, err
+	}
+	img, err = processingPipeline(opts).Apply(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	targetWidth, targetHeight := photoSize(bounds.Dx(), bounds.Dy(), opts)
+	focal := cropFocalPoint(img, targetWidth, targetHeight, opts.CropAnchor, opts.FocalPoint)
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, imaging.Fit(img, maxSize, maxSize, imaging.Linear), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return &CropPreview{
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		Crop:       cropRect(bounds, targetWidth, targetHeight, focal),
+		FocalPoint: focal,
+		Thumbnail:  thumb.Bytes(),
+	}, nil
+}
+
+// fillImage scales img to cover targetWidth x targetHeight and crops the
+// overflow around the crop anchor
+func fillImage(img image.Image, targetWidth, targetHeight int, anchor CropAnchor, focal FocalPoint) image.Image {
+	focal = cropFocalPoint(img, targetWidth, targetHeight, anchor, focal)
+	cropped := imaging.Crop(img, cropRect(img.Bounds(), targetWidth, targetHeight, focal))
+	return imaging.Resize(cropped, targetWidth, targetHeight, imaging.Lanczos)
+}
+
+// cropFocalPoint resolves the crop anchor to the point the crop is
+// centered on
+func cropFocalPoint(img image.Image, targetWidth, targetHeight int, anchor CropAnchor, focal FocalPoint) FocalPoint {
+	switch anchor {
+	case CropFocal:
+		if !focal.Valid() {
+			return CenterFocalPoint
+		}
+		return focal
+	case CropSmart:
+		return smartFocalPoint(img, targetWidth, targetHeight)
+	default:
+		return CenterFocalPoint
+	}
+}
+
+// cropSize returns the largest size with the aspect ratio of
+// targetWidth x targetHeight that fits inside width x height
+func cropSize(width, height, targetWidth, targetHeight int) (int, int) {
+	if width*targetHeight > height*targetWidth {
+		// Wider than the target: keep the full height
+		w := int(math.Round(float64(height) * float64(targetWidth) / float64(targetHeight)))
+		return max(1, min(w, width)), height
+	}
+	h := int(math.Round(float64(width) * float64(targetHeight) / float64(targetWidth)))
+	return width, max(1, min(h, height))
+}
+
+// cropRect returns the region of bounds with the target aspect ratio that
+// is centered on focal, shifted inside bounds where necessary
+func cropRect(bounds image.Rectangle, targetWidth, targetHeight int, focal FocalPoint) image.Rectangle {
+	width, height := bounds.Dx(), bounds.Dy()
+	cw, ch := cropSize(width, height, targetWidth, targetHeight)
+
+	x := int(math.Round(focal.X*float64(width) - float64(cw)/2))
+	y := int(math.Round(focal.Y*float64(height) - float64(ch)/2))
+	x = max(0, min(x, width-cw))
+	y = max(0, min(y, height-ch))
+
+	return image.Rect(x, y, x+cw, y+ch).Add(bounds.Min)
+}
+
+// smartFocalPoint finds the crop window with the most edge energy in a
+// downscaled copy of img and returns its center. Windows with equal energy
+// are resolved towards the center, so featureless images are center
+// cropped.
+func smartFocalPoint(img image.Image, targetWidth, targetHeight int) FocalPoint {
+	small := imaging.Fit(img, smartCropAnalysisSize, smartCropAnalysisSize, imaging.Box)
+	width, height := small.Bounds().Dx(), small.Bounds().Dy()
+	cw, ch := cropSize(width, height, targetWidth, targetHeight)
+	if cw == width && ch == height {
+		return CenterFocalPoint
+	}
+
+	energy := edgeEnergy(small)
+
+	// Summed-area table, so that every window is summed in constant time
+	stride := width + 1
+	sums := make([]float64, stride*(height+1))
+	for y := 0; y < height; y++ {
+		row := 0.0
+		for x := 0; x < width; x++ {
+			row += energy[y*width+x]
+			sums[(y+1)*stride+x+1] = sums[y*stride+x+1] + row
+		}
+	}
+
+	centerX := float64(width-cw) / 2
+	centerY := float64(height-ch) / 2
+	bestX, bestY := 0, 0
+	bestScore, bestDist := -1.0, math.Inf(1)
+
+	for y := 0; y+ch <= height; y++ {
+		for x := 0; x+cw <= width; x++ {
+			score := sums[(y+ch)*stride+x+cw] - sums[y*stride+x+cw] - sums[(y+ch)*stride+x] + sums[y*stride+x]
+			dist := math.Hypot(float64(x)-centerX, float64(y)-centerY)
+
+			// Scores are sums of quantized values; compare with some slack
+			const epsilon = 1e-6
+			if score > bestScore+epsilon || (math.Abs(score-bestScore) <= epsilon && dist < bestDist) {
+				bestX, bestY = x, y
+				bestScore, bestDist = score, dist
+			}
+		}
+	}
+
+	return FocalPoint{
+		X: (float64(bestX) + float64(cw)/2) / float64(width),
+		Y: (float64(bestY) + float64(ch)/2) / float64(height),
+	}
+}
+
+// edgeEnergy returns the luminance gradient magnitude of every pixel
+func edgeEnergy(img *image.NRGBA) []float64 {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	luma := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*img.Stride + x*4
+			r, g, b := float64(img.Pix[i]), float64(img.Pix[i+1]), float64(img.Pix[i+2])
+			luma[y*width+x] = (0.299*r + 0.587*g + 0.114*b) / 255
+		}
+	}
+
+	at := func(x, y int) float64 {
+		x = max(0, min(x, width-1))
+		y = max(0, min(y, height-1))
+		return luma[y*width+x]
+	}
+
+	energy := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx := at(x+1, y) - at(x-1, y)
+			dy := at(x, y+1) - at(x, y-1)
+			energy[y*width+x] = math.Hypot(dx, dy)
+		}
+	}
+	return energy
+}
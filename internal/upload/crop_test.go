@@ -0,0 +1,146 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCropAnchor(t *testing.T) {
+	anchor, err := ParseCropAnchor("")
+	require.NoError(t, err)
+	assert.Equal(t, CropCenter, anchor)
+
+	anchor, err = ParseCropAnchor("Smart")
+	require.NoError(t, err)
+	assert.Equal(t, CropSmart, anchor)
+
+	_, err = ParseCropAnchor("top")
+	assert.Error(t, err)
+}
+
+func TestCropRect(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   image.Rectangle
+		focal    FocalPoint
+		expected image.Rectangle
+	}{
+		{
+			name:     "4:3 center",
+			bounds:   image.Rect(0, 0, 1600, 1200),
+			focal:    CenterFocalPoint,
+			expected: image.Rect(0, 150, 1600, 1050),
+		},
+		{
+			name:     "4:3 focal near the top is clamped",
+			bounds:   image.Rect(0, 0, 1600, 1200),
+			focal:    FocalPoint{X: 0.5, Y: 0.1},
+			expected: image.Rect(0, 0, 1600, 900),
+		},
+		{
+			name:     "21:9 focal near the right is clamped",
+			bounds:   image.Rect(0, 0, 2100, 900),
+			focal:    FocalPoint{X: 0.7, Y: 0.5},
+			expected: image.Rect(500, 0, 2100, 900),
+		},
+		{
+			name:     "Offset bounds",
+			bounds:   image.Rect(100, 100, 2200, 1000),
+			focal:    FocalPoint{X: 0, Y: 0.5},
+			expected: image.Rect(100, 100, 1700, 1000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := printSize(tt.bounds)
+			assert.Equal(t, tt.expected, cropRect(tt.bounds, width, height, tt.focal))
+		})
+	}
+}
+
+func TestSmartFocalPoint(t *testing.T) {
+	t.Run("Detail off-center", func(t *testing.T) {
+		// Flat 21:9 image with a busy patch on the right
+		img := createFlatImage(2100, 900)
+		subject := image.Rect(1700, 300, 1900, 600)
+		drawChecker(img, subject, 10)
+
+		focal := smartFocalPoint(img, Print1080pWidth, Print1080pHeight)
+		crop := cropRect(img.Bounds(), Print1080pWidth, Print1080pHeight, focal)
+		assert.True(t, subject.In(crop), "crop %v should contain %v", crop, subject)
+	})
+
+	t.Run("Featureless image is center cropped", func(t *testing.T) {
+		focal := smartFocalPoint(createFlatImage(2100, 900), Print1080pWidth, Print1080pHeight)
+		assert.InDelta(t, 0.5, focal.X, 0.01)
+		assert.InDelta(t, 0.5, focal.Y, 0.01)
+	})
+}
+
+func TestPreviewCrop(t *testing.T) {
+	tempDir := t.TempDir()
+	imagePath := filepath.Join(tempDir, "test.png")
+
+	img := createFlatImage(2100, 900)
+	subject := image.Rect(100, 300, 300, 600)
+	drawChecker(img, subject, 10)
+	file, err := os.Create(imagePath)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+
+	preview, err := PreviewCrop(context.Background(), Options{ImagePath: imagePath, CropAnchor: CropSmart}, 512)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2100, preview.Width)
+	assert.Equal(t, 900, preview.Height)
+	assert.Equal(t, 1600, preview.Crop.Dx())
+	assert.Equal(t, 900, preview.Crop.Dy())
+	assert.True(t, subject.In(preview.Crop))
+	assert.Less(t, preview.FocalPoint.X, 0.5)
+
+	// With a frame, the crop takes the aspect ratio of its 1834x821 opening
+	frame := &FrameTemplate{Name: "polaroid", Border: FrameBorder{Top: 0.04, Right: 0.04, Bottom: 0.2, Left: 0.04}}
+	framed, err := PreviewCrop(context.Background(), Options{ImagePath: imagePath, CropAnchor: CropSmart, Frame: frame}, 512)
+	require.NoError(t, err)
+	assert.Equal(t, 900, framed.Crop.Dy())
+	assert.InDelta(t, 1834.0/821.0, float64(framed.Crop.Dx())/float64(framed.Crop.Dy()), 0.005)
+	assert.True(t, subject.In(framed.Crop))
+
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(preview.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 512, thumb.Width)
+}
+
+// createFlatImage creates a uniformly gray image
+func createFlatImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 128, 128, 128, 255
+	}
+	return img
+}
+
+// drawChecker draws a black and white checkerboard into rect
+func drawChecker(img *image.NRGBA, rect image.Rectangle, size int) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			c := color.NRGBA{A: 255}
+			if (x/size+y/size)%2 == 0 {
+				c = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportManifest describes the request a dry run would have sent
+type ExportManifest struct {
+	Method   string `json:"method"`
+	Endpoint string `json:"endpoint"`
+	// ContentLength is the size of the multipart form in bytes
+	ContentLength int64         `json:"contentLength"`
+	Fields        []ExportField `json:"fields"`
+	Image         ExportImage   `json:"image"`
+}
+
+// ExportField is a text field of the multipart form, in the order it is sent
+type ExportField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ExportImage describes the image part of the multipart form
+type ExportImage struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	// File is the name of the exported PNG next to the manifest
+	File     string   `json:"file"`
+	Width    int      `json:"width"`
+	Height   int      `json:"height"`
+	Size     int      `json:"size"`
+	SHA256   string   `json:"sha256"`
+	Strategy Strategy `json:"strategy"`
+}
+
+// Export prepares the image like Upload and writes it and the manifest of
+// the form it would be sent in to opts.ExportDir. It needs no client, so
+// dry runs work without logging in.
+func Export(ctx context.Context, opts Options) (*UploadResult, error) {
+	if opts.ExportDir == "" {
+		return nil, errors.New("export directory is required")
+	}
+	opts.CaptureTime = captureTime(opts)
+
+	imageData, encoding, err := (&Uploader{}).prepareImage(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare image: %w", err)
+	}
+	return export(imageData, encoding, opts)
+}
+
+// export writes imageData and the manifest of the form it would be sent in
+// to opts.ExportDir instead of uploading it
+func export(imageData []byte, encoding *EncodeResult, opts Options) (*UploadResult, error) {
+	body, err := newMultipartBody(imageData, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create multipart form: %w", err)
+	}
+
+	if err := os.MkdirAll(opts.ExportDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	stem := strings.TrimSuffix(body.filename, filepath.Ext(body.filename))
+	imagePath, manifestPath, err := exportPaths(opts.ExportDir, stem)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(imageData)
+	manifest := ExportManifest{
+		Method:        http.MethodPost,
+		Endpoint:      UploadEndpoint,
+		ContentLength: body.size,
+		Fields:        make([]ExportField, 0, len(body.fields)),
+		Image: ExportImage{
+			Field:       "image",
+			Filename:    body.filename,
+			ContentType: "image/png",
+			File:        filepath.Base(imagePath),
+			Width:       encoding.Width,
+			Height:      encoding.Height,
+			Size:        len(imageData),
+			SHA256:      hex.EncodeToString(sum[:]),
+			Strategy:    encoding.Strategy,
+		},
+	}
+	for _, field := range body.fields {
+		manifest.Fields = append(manifest.Fields, ExportField{Name: field.name, Value: field.value})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := writeNewFile(imagePath, imageData); err != nil {
+		return nil, fmt.Errorf("failed to write exported image: %w", err)
+	}
+	if err := writeNewFile(manifestPath, append(data, '\n')); err != nil {
+		// Don't leave an image without its manifest behind
+		os.Remove(imagePath)
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return &UploadResult{
+		AuthorName:   opts.Metadata.Author,
+		CreatedAt:    body.timestamp,
+		WorldID:      opts.WorldID,
+		WorldName:    opts.WorldName,
+		Encoding:     encoding,
+		ExportPath:   imagePath,
+		ManifestPath: manifestPath,
+	}, nil
+}
+
+// exportPaths returns the paths of the exported PNG and manifest of stem in
+// dir. An earlier export of the same file isn't overwritten; the new one is
+// numbered instead, as in stem-2.print.png.
+func exportPaths(dir, stem string) (string, string, error) {
+	for n := 1; ; n++ {
+		name := stem
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", stem, n)
+		}
+		imagePath := filepath.Join(dir, name+".print.png")
+		manifestPath := filepath.Join(dir, name+".print.json")
+
+		taken := false
+		for _, path := range []string{imagePath, manifestPath} {
+			if _, err := os.Lstat(path); err == nil {
+				taken = true
+			} else if !os.IsNotExist(err) {
+				return "", "", fmt.Errorf("failed to check export file: %w", err)
+			}
+		}
+		if !taken {
+			return imagePath, manifestPath, nil
+		}
+	}
+}
+
+// writeNewFile writes data to a file at path that must not exist yet. A
+// partly written file is removed.
+func writeNewFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload_DryRun(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "VRChat_shot.jpg")
+	require.NoError(t, createTestImage(imagePath, "jpeg", 400, 300))
+
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	exportDir := filepath.Join(t.TempDir(), "export")
+	timestamp := time.Date(2024, 3, 15, 21, 30, 5, 0, time.FixedZone("JST", 9*60*60))
+	uploader := New(client)
+	result, err := uploader.Upload(context.Background(), Options{
+		ImagePath: imagePath,
+		Note:      "メモ",
+		WorldID:   "wrld_12345",
+		WorldName: "Test World",
+		Timestamp: timestamp,
+		ExportDir: exportDir,
+		Metadata:  MetadataOptions{Author: "yoshiken"},
+	})
+	require.NoError(t, err)
+
+	// Nothing is sent
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+
+	assert.Empty(t, result.FileID)
+	assert.Equal(t, "yoshiken", result.AuthorName)
+	assert.Equal(t, "wrld_12345", result.WorldID)
+	assert.Equal(t, "Test World", result.WorldName)
+	assert.True(t, timestamp.Equal(result.CreatedAt))
+	require.NotNil(t, result.Encoding)
+	assert.Equal(t, filepath.Join(exportDir, "VRChat_shot.print.png"), result.ExportPath)
+	assert.Equal(t, filepath.Join(exportDir, "VRChat_shot.print.json"), result.ManifestPath)
+
+	// The exported PNG is what would have been uploaded
+	imageData, err := os.ReadFile(result.ExportPath)
+	require.NoError(t, err)
+	assert.Equal(t, result.Encoding.Size, len(imageData))
+	img, err := png.Decode(bytes.NewReader(imageData))
+	require.NoError(t, err)
+	assert.Equal(t, result.Encoding.Width, img.Bounds().Dx())
+	metadata, err := ReadMetadata(bytes.NewReader(imageData))
+	require.NoError(t, err)
+	assert.Equal(t, "メモ", metadata.Note)
+
+	data, err := os.ReadFile(result.ManifestPath)
+	require.NoError(t, err)
+	var manifest ExportManifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+
+	sum := sha256.Sum256(imageData)
+	assert.Equal(t, "POST", manifest.Method)
+	assert.Equal(t, UploadEndpoint, manifest.Endpoint)
+	assert.Greater(t, manifest.ContentLength, int64(len(imageData)))
+	assert.Equal(t, []ExportField{
+		{Name: "timestamp", Value: "2024-03-15T21:30:05+09:00"},
+		{Name: "note", Value: "メモ"},
+		{Name: "worldId", Value: "wrld_12345"},
+		{Name: "worldName", Value: "Test World"},
+	}, manifest.Fields)
+	assert.Equal(t, ExportImage{
+		Field:       "image",
+		Filename:    "VRChat_shot.jpg",
+		ContentType: "image/png",
+		File:        "VRChat_shot.print.png",
+		Width:       result.Encoding.Width,
+		Height:      result.Encoding.Height,
+		Size:        len(imageData),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Strategy:    result.Encoding.Strategy,
+	}, manifest.Image)
+}
+
+func TestExport_KeepsEarlierExports(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "test.png")
+	require.NoError(t, createTestImage(imagePath, "png", 64, 64))
+	exportDir := t.TempDir()
+
+	// Exports need no client
+	first, err := Export(context.Background(), Options{ImagePath: imagePath, ResizeMode: ResizeOriginal, Note: "first", ExportDir: exportDir})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(exportDir, "test.print.png"), first.ExportPath)
+
+	// A stray manifest also takes the name
+	require.NoError(t, os.WriteFile(filepath.Join(exportDir, "test-2.print.json"), []byte("{}"), 0644))
+
+	second, err := Export(context.Background(), Options{ImagePath: imagePath, ResizeMode: ResizeOriginal, Note: "second", ExportDir: exportDir})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(exportDir, "test-3.print.png"), second.ExportPath)
+	assert.Equal(t, filepath.Join(exportDir, "test-3.print.json"), second.ManifestPath)
+
+	data, err := os.ReadFile(first.ExportPath)
+	require.NoError(t, err)
+	metadata, err := ReadMetadata(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "first", metadata.Note)
+
+	_, err = Export(context.Background(), Options{ImagePath: imagePath})
+	assert.Error(t, err)
+}
+
+func TestUpload_DryRunError(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "test.png")
+	require.NoError(t, createTestImage(imagePath, "png", 64, 64))
+
+	// The export directory can't be created below a file
+	exportDir := filepath.Join(imagePath, "export")
+	_, err := New(resty.New()).Upload(context.Background(), Options{ImagePath: imagePath, ExportDir: exportDir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create export directory")
+}
@@ -0,0 +1,436 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Filter is a step of the image processing pipeline
+type Filter interface {
+	Apply(img image.Image) image.Image
+}
+
+// FilterFunc adapts a function to the Filter interface
+type FilterFunc func(img image.Image) image.Image
+
+func (f FilterFunc) Apply(img image.Image) image.Image {
+	return f(img)
+}
+
+// sizeFilter is implemented by filters that change the dimensions of the
+// image, so that Inspect can predict the output size without decoding
+type sizeFilter interface {
+	Size(width, height int) (int, int)
+}
+
+// validatingFilter is implemented by filters whose parameters can be invalid
+type validatingFilter interface {
+	Validate() error
+}
+
+// Pipeline is an ordered list of filters
+type Pipeline []Filter
+
+// Apply runs the filters in order, stopping if ctx is cancelled
+func (p Pipeline) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	for _, f := range p {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		img = f.Apply(img)
+	}
+	return img, ctx.Err()
+}
+
+// Size predicts the dimensions of a width x height image after the pipeline
+func (p Pipeline) Size(width, height int) (int, int) {
+	for _, f := range p {
+		if s, ok := f.(sizeFilter); ok {
+			width, height = s.Size(width, height)
+		}
+	}
+	return width, height
+}
+
+// checkSize returns an ImageTooLargeError if a width x height image grows
+// beyond limits at any step of the pipeline
+func (p Pipeline) checkSize(width, height int, limits Limits) error {
+	for i, f := range p {
+		s, ok := f.(sizeFilter)
+		if !ok {
+			continue
+		}
+		width, height = s.Size(width, height)
+		if err := limits.check(image.Config{Width: width, Height: height, ColorModel: color.NRGBAModel}); err != nil {
+			return fmt.Errorf("filter %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the parameters of the filters
+func (p Pipeline) Validate() error {
+	for i, f := range p {
+		if v, ok := f.(validatingFilter); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("filter %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateProcessing checks the orientation, filter, frame and watermark
+// options
+func validateProcessing(opts Options) error {
+	if err := validateOrientation(opts); err != nil {
+		return err
+	}
+	if err := Pipeline(opts.Filters).Validate(); err != nil {
+		return err
+	}
+	if opts.Frame != nil {
+		if err := opts.Frame.Validate(); err != nil {
+			return err
+		}
+	}
+	for i, w := range opts.Watermarks {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("watermark %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// checkFilterSize checks that the filters in opts don't enlarge an upright
+// width x height image beyond opts.Limits. Limits only guards the decoded
+// input, so without this a resize filter could allocate any amount of
+// memory.
+func checkFilterSize(opts Options, width, height int) error {
+	if opts.Rotate == Rotate90 || opts.Rotate == Rotate270 {
+		width, height = height, width
+	}
+	return Pipeline(opts.Filters).checkSize(width, height, opts.Limits)
+}
+
+// processingPipeline returns the steps that prepare the image in opts for
+// fitting it to the print size: the manual orientation, the filters in
+// opts.Filters and the conversion to 8-bit color
+func processingPipeline(opts Options) Pipeline {
+	steps := Pipeline{FilterFunc(func(img image.Image) image.Image {
+		return orientImage(img, opts)
+	})}
+	steps = append(steps, opts.Filters...)
+	return append(steps, FilterFunc(func(img image.Image) image.Image {
+		return normalizeImage(img, opts)
+	}))
+}
+
+// uploadPipeline returns every step Upload applies to the decoded image:
+// the processing pipeline, the fit to the print size, the frame and the
+// watermarks, whose images are loaded here
+func uploadPipeline(opts Options) (Pipeline, error) {
+	steps := append(processingPipeline(opts), FilterFunc(func(img image.Image) image.Image {
+		return resizeImage(img, opts)
+	}))
+	if opts.Frame != nil {
+		text := CaptionText{Note: opts.Note, World: opts.WorldName, Date: captureTime(opts)}
+		f, err := opts.Frame.Filter(text)
+		if err != nil {
+			return nil, fmt.Errorf("frame %s: %w", opts.Frame.Name, err)
+		}
+		steps = append(steps, f)
+	}
+	for i, w := range opts.Watermarks {
+		f, err := w.Filter()
+		if err != nil {
+			return nil, fmt.Errorf("watermark %d: %w", i+1, err)
+		}
+		steps = append(steps, f)
+	}
+	return steps, nil
+}
+
+// RotateFilter rotates the image clockwise by Angle degrees. Angles that
+// aren't a multiple of 90 enlarge the image to fit the rotated corners,
+// which are left transparent.
+type RotateFilter struct {
+	Angle float64
+}
+
+func (f RotateFilter) Apply(img image.Image) image.Image {
+	switch math.Mod(math.Mod(f.Angle, 360)+360, 360) {
+	case 0:
+		return img
+	case 90:
+		return imaging.Rotate270(img)
+	case 180:
+		return imaging.Rotate180(img)
+	case 270:
+		return imaging.Rotate90(img)
+	}
+	// imaging rotates counter-clockwise
+	return imaging.Rotate(img, -f.Angle, color.Transparent)
+}
+
+func (f RotateFilter) Size(width, height int) (int, int) {
+	switch math.Mod(math.Mod(f.Angle, 360)+360, 360) {
+	case 0, 180:
+		return width, height
+	case 90, 270:
+		return height, width
+	}
+	return rotatedSize(width, height, -f.Angle)
+}
+
+// rotatedSize matches the size of imaging.Rotate's result
+func rotatedSize(width, height int, angle float64) (int, int) {
+	if width <= 0 || height <= 0 {
+		return 0, 0
+	}
+
+	sin, cos := math.Sincos(math.Pi * angle / 180)
+	w, h := float64(width-1), float64(height-1)
+	xs := []float64{0, w * cos, w*cos - h*sin, -h * sin}
+	ys := []float64{0, w * sin, w*sin + h*cos, h * cos}
+
+	extent := func(vs []float64) int {
+		lo, hi := vs[0], vs[0]
+		for _, v := range vs[1:] {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		size := hi - lo + 1
+		if size-math.Floor(size) > 0.1 {
+			size++
+		}
+		return int(size)
+	}
+	return extent(xs), extent(ys)
+}
+
+// CropFilter keeps a rectangle of the image. X, Y, Width and Height are
+// normalized to the image size, so that a crop works for any resolution.
+type CropFilter struct {
+	X, Y, Width, Height float64
+}
+
+func (f CropFilter) Validate() error {
+	if f.X < 0 || f.Y < 0 || f.Width <= 0 || f.Height <= 0 || f.X+f.Width > 1 || f.Y+f.Height > 1 {
+		return fmt.Errorf("invalid crop: %.2f, %.2f %.2fx%.2f (must lie within 0-1)", f.X, f.Y, f.Width, f.Height)
+	}
+	return nil
+}
+
+func (f CropFilter) Apply(img image.Image) image.Image {
+	return imaging.Crop(img, f.rect(img.Bounds()))
+}
+
+func (f CropFilter) Size(width, height int) (int, int) {
+	r := f.rect(image.Rect(0, 0, width, height))
+	return r.Dx(), r.Dy()
+}
+
+// rect converts the crop to pixels within bounds, keeping at least 1 pixel
+func (f CropFilter) rect(bounds image.Rectangle) image.Rectangle {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	x0 := bounds.Min.X + int(math.Round(f.X*w))
+	y0 := bounds.Min.Y + int(math.Round(f.Y*h))
+	x1 := max(x0+1, bounds.Min.X+int(math.Round((f.X+f.Width)*w)))
+	y1 := max(y0+1, bounds.Min.Y+int(math.Round((f.Y+f.Height)*h)))
+	return image.Rect(x0, y0, x1, y1).Intersect(bounds)
+}
+
+// ResizeFilter scales the image to Width x Height pixels. If one of them is
+// zero it is derived from the other, keeping the aspect ratio.
+type ResizeFilter struct {
+	Width, Height int
+}
+
+func (f ResizeFilter) Validate() error {
+	if f.Width < 0 || f.Height < 0 || (f.Width == 0 && f.Height == 0) {
+		return fmt.Errorf("invalid resize: %dx%d", f.Width, f.Height)
+	}
+	return nil
+}
+
+func (f ResizeFilter) Apply(img image.Image) image.Image {
+	return imaging.Resize(img, f.Width, f.Height, imaging.Lanczos)
+}
+
+func (f ResizeFilter) Size(width, height int) (int, int) {
+	switch {
+	case f.Width == 0 && height > 0:
+		return max(1, int(math.Round(float64(width)*float64(f.Height)/float64(height)))), f.Height
+	case f.Height == 0 && width > 0:
+		return f.Width, max(1, int(math.Round(float64(height)*float64(f.Width)/float64(width))))
+	}
+	return f.Width, f.Height
+}
+
+// BrightnessFilter changes the brightness by Percent (-100 to 100)
+type BrightnessFilter struct {
+	Percent float64
+}
+
+func (f BrightnessFilter) Validate() error {
+	return validatePercent("brightness", f.Percent)
+}
+
+func (f BrightnessFilter) Apply(img image.Image) image.Image {
+	return imaging.AdjustBrightness(img, f.Percent)
+}
+
+// ContrastFilter changes the contrast by Percent (-100 to 100)
+type ContrastFilter struct {
+	Percent float64
+}
+
+func (f ContrastFilter) Validate() error {
+	return validatePercent("contrast", f.Percent)
+}
+
+func (f ContrastFilter) Apply(img image.Image) image.Image {
+	return imaging.AdjustContrast(img, f.Percent)
+}
+
+// SaturationFilter changes the saturation by Percent (-100 to 100); -100
+// gives a grayscale image
+type SaturationFilter struct {
+	Percent float64
+}
+
+func (f SaturationFilter) Validate() error {
+	return validatePercent("saturation", f.Percent)
+}
+
+func (f SaturationFilter) Apply(img image.Image) image.Image {
+	return imaging.AdjustSaturation(img, f.Percent)
+}
+
+func validatePercent(name string, percent float64) error {
+	if percent < -100 || percent > 100 {
+		return fmt.Errorf("invalid %s: %g%% (must be between -100 and 100)", name, percent)
+	}
+	return nil
+}
+
+// SharpenFilter sharpens the image with an unsharp mask of the given
+// Gaussian Sigma
+type SharpenFilter struct {
+	Sigma float64
+}
+
+func (f SharpenFilter) Validate() error {
+	return validateSigma("sharpen", f.Sigma)
+}
+
+func (f SharpenFilter) Apply(img image.Image) image.Image {
+	return imaging.Sharpen(img, f.Sigma)
+}
+
+// BlurFilter applies a Gaussian blur with the given Sigma
+type BlurFilter struct {
+	Sigma float64
+}
+
+func (f BlurFilter) Validate() error {
+	return validateSigma("blur", f.Sigma)
+}
+
+func (f BlurFilter) Apply(img image.Image) image.Image {
+	return imaging.Blur(img, f.Sigma)
+}
+
+// maxSigma keeps blur and sharpen kernels to a reasonable size
+const maxSigma = 50
+
+func validateSigma(name string, sigma float64) error {
+	if sigma <= 0 || sigma > maxSigma {
+		return fmt.Errorf("invalid %s sigma: %g (must be between 0 and %d)", name, sigma, maxSigma)
+	}
+	return nil
+}
+
+// FilterType names a filter in a FilterSpec
+type FilterType string
+
+const (
+	FilterRotate     FilterType = "rotate"
+	FilterCrop       FilterType = "crop"
+	FilterResize     FilterType = "resize"
+	FilterBrightness FilterType = "brightness"
+	FilterContrast   FilterType = "contrast"
+	FilterSaturation FilterType = "saturation"
+	FilterSharpen    FilterType = "sharpen"
+	FilterBlur       FilterType = "blur"
+)
+
+// FilterSpec is the serializable form of a filter, for presets and the GUI.
+// Which fields apply depends on Type.
+type FilterSpec struct {
+	Type FilterType `json:"type"`
+	// Angle is the clockwise rotation in degrees for FilterRotate
+	Angle float64 `json:"angle,omitempty"`
+	// X, Y, Width and Height are the normalized rectangle for FilterCrop.
+	// Width and Height are the size in pixels for FilterResize.
+	X      float64 `json:"x,omitempty"`
+	Y      float64 `json:"y,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	// Amount is the percentage for FilterBrightness, FilterContrast and
+	// FilterSaturation, and the sigma for FilterSharpen and FilterBlur
+	Amount float64 `json:"amount,omitempty"`
+}
+
+// Filter builds and validates the filter described by s
+func (s FilterSpec) Filter() (Filter, error) {
+	var f Filter
+	switch s.Type {
+	case FilterRotate:
+		f = RotateFilter{Angle: s.Angle}
+	case FilterCrop:
+		f = CropFilter{X: s.X, Y: s.Y, Width: s.Width, Height: s.Height}
+	case FilterResize:
+		if s.Width != math.Trunc(s.Width) || s.Height != math.Trunc(s.Height) {
+			return nil, fmt.Errorf("invalid resize: %gx%g (must be whole pixels)", s.Width, s.Height)
+		}
+		f = ResizeFilter{Width: int(s.Width), Height: int(s.Height)}
+	case FilterBrightness:
+		f = BrightnessFilter{Percent: s.Amount}
+	case FilterContrast:
+		f = ContrastFilter{Percent: s.Amount}
+	case FilterSaturation:
+		f = SaturationFilter{Percent: s.Amount}
+	case FilterSharpen:
+		f = SharpenFilter{Sigma: s.Amount}
+	case FilterBlur:
+		f = BlurFilter{Sigma: s.Amount}
+	default:
+		return nil, fmt.Errorf("invalid filter type: %q", s.Type)
+	}
+
+	if v, ok := f.(validatingFilter); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// ParseFilters builds the filters described by specs, in order
+func ParseFilters(specs []FilterSpec) ([]Filter, error) {
+	filters := make([]Filter, 0, len(specs))
+	for i, spec := range specs {
+		f, err := spec.Filter()
+		if err != nil {
+			return nil, fmt.Errorf("filter %d: %w", i+1, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
@@ -0,0 +1,209 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		specs       []FilterSpec
+		expected    []Filter
+		expectError string
+	}{
+		{
+			name:     "Empty",
+			expected: []Filter{},
+		},
+		{
+			name: "All filter types in order",
+			specs: []FilterSpec{
+				{Type: FilterRotate, Angle: 90},
+				{Type: FilterCrop, X: 0.1, Y: 0.2, Width: 0.5, Height: 0.5},
+				{Type: FilterResize, Width: 800},
+				{Type: FilterBrightness, Amount: 10},
+				{Type: FilterContrast, Amount: -20},
+				{Type: FilterSaturation, Amount: 30},
+				{Type: FilterSharpen, Amount: 1.5},
+				{Type: FilterBlur, Amount: 2},
+			},
+			expected: []Filter{
+				RotateFilter{Angle: 90},
+				CropFilter{X: 0.1, Y: 0.2, Width: 0.5, Height: 0.5},
+				ResizeFilter{Width: 800},
+				BrightnessFilter{Percent: 10},
+				ContrastFilter{Percent: -20},
+				SaturationFilter{Percent: 30},
+				SharpenFilter{Sigma: 1.5},
+				BlurFilter{Sigma: 2},
+			},
+		},
+		{
+			name:        "Unknown type",
+			specs:       []FilterSpec{{Type: "sepia"}},
+			expectError: `filter 1: invalid filter type: "sepia"`,
+		},
+		{
+			name:        "Crop outside the image",
+			specs:       []FilterSpec{{Type: FilterRotate, Angle: 10}, {Type: FilterCrop, X: 0.6, Width: 0.5, Height: 1}},
+			expectError: "filter 2: invalid crop",
+		},
+		{
+			name:        "Resize without a size",
+			specs:       []FilterSpec{{Type: FilterResize}},
+			expectError: "invalid resize",
+		},
+		{
+			name:        "Fractional resize",
+			specs:       []FilterSpec{{Type: FilterResize, Width: 100.5}},
+			expectError: "must be whole pixels",
+		},
+		{
+			name:        "Brightness out of range",
+			specs:       []FilterSpec{{Type: FilterBrightness, Amount: 150}},
+			expectError: "invalid brightness",
+		},
+		{
+			name:        "Blur without sigma",
+			specs:       []FilterSpec{{Type: FilterBlur}},
+			expectError: "invalid blur sigma",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := ParseFilters(tt.specs)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, filters)
+		})
+	}
+}
+
+func TestFilters_Size(t *testing.T) {
+	img := createPatternImage(300, 200)
+
+	tests := []struct {
+		name   string
+		filter Filter
+	}{
+		{name: "Rotate 90", filter: RotateFilter{Angle: 90}},
+		{name: "Rotate -90", filter: RotateFilter{Angle: -90}},
+		{name: "Rotate 180", filter: RotateFilter{Angle: 180}},
+		{name: "Rotate 30", filter: RotateFilter{Angle: 30}},
+		{name: "Rotate -7.5", filter: RotateFilter{Angle: -7.5}},
+		{name: "Crop", filter: CropFilter{X: 0.25, Y: 0.1, Width: 0.5, Height: 0.333}},
+		{name: "Crop a sliver", filter: CropFilter{X: 0.5, Y: 0.5, Width: 0.001, Height: 0.001}},
+		{name: "Resize", filter: ResizeFilter{Width: 150, Height: 40}},
+		{name: "Resize to width", filter: ResizeFilter{Width: 100}},
+		{name: "Resize to height", filter: ResizeFilter{Height: 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.filter.Apply(img)
+			width, height := Pipeline{tt.filter}.Size(300, 200)
+			assert.Equal(t, result.Bounds().Dx(), width)
+			assert.Equal(t, result.Bounds().Dy(), height)
+		})
+	}
+}
+
+func TestFilters_Adjust(t *testing.T) {
+	orange := color.NRGBA{R: 200, G: 120, B: 40, A: 255}
+	img := paintImage(image.NewNRGBA(image.Rect(0, 0, 8, 8)), orange)
+
+	pixel := func(f Filter) color.NRGBA {
+		return color.NRGBAModel.Convert(f.Apply(img).At(4, 4)).(color.NRGBA)
+	}
+
+	brighter := pixel(BrightnessFilter{Percent: 20})
+	assert.Greater(t, brighter.G, orange.G)
+
+	lessContrast := pixel(ContrastFilter{Percent: -50})
+	assert.Less(t, int(lessContrast.R)-int(lessContrast.B), int(orange.R)-int(orange.B))
+
+	gray := pixel(SaturationFilter{Percent: -100})
+	assert.InDelta(t, gray.R, gray.G, 1)
+	assert.InDelta(t, gray.G, gray.B, 1)
+}
+
+func TestFilters_SharpenAndBlur(t *testing.T) {
+	// A vertical edge between black and white
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 8))
+	for y := 0; y < 8; y++ {
+		for x := 16; x < 32; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	blurred := color.GrayModel.Convert(BlurFilter{Sigma: 2}.Apply(img).At(15, 4)).(color.Gray)
+	assert.Greater(t, blurred.Y, uint8(32), "blur spreads the edge")
+
+	// Sharpening a soft edge increases its contrast
+	soft := BlurFilter{Sigma: 2}.Apply(img)
+	before := color.GrayModel.Convert(soft.At(14, 4)).(color.Gray)
+	after := color.GrayModel.Convert(SharpenFilter{Sigma: 2}.Apply(soft).At(14, 4)).(color.Gray)
+	assert.Less(t, after.Y, before.Y)
+}
+
+func TestPipeline_Apply(t *testing.T) {
+	var order []string
+	step := func(name string) Filter {
+		return FilterFunc(func(img image.Image) image.Image {
+			order = append(order, name)
+			return img
+		})
+	}
+
+	img := createPatternImage(10, 10)
+	result, err := Pipeline{step("a"), step("b"), step("c")}.Apply(context.Background(), img)
+	require.NoError(t, err)
+	assert.Same(t, img, result)
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Pipeline{step("d")}.Apply(ctx, img)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestPrepareImage_Filters(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "test.png")
+	require.NoError(t, createTestImage(imagePath, "png", 400, 300))
+
+	uploader := &Uploader{}
+
+	// Filters run before the print resize
+	data, _, err := uploader.prepareImage(context.Background(), Options{
+		ImagePath:  imagePath,
+		ResizeMode: ResizeOriginal,
+		Filters:    []Filter{CropFilter{Width: 0.5, Height: 1}, RotateFilter{Angle: 90}},
+	})
+	require.NoError(t, err)
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 300, cfg.Width)
+	assert.Equal(t, 200, cfg.Height)
+
+	_, _, err = uploader.prepareImage(context.Background(), Options{
+		ImagePath: imagePath,
+		Filters:   []Filter{BlurFilter{}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid blur sigma")
+}
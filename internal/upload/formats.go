@@ -0,0 +1,16 @@
+package upload
+
+import (
+	// Decoders for every supported input format
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// SupportedExtensions lists the file extensions of the input formats the
+// uploader can decode. All images are converted to PNG for upload.
+var SupportedExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".bmp", ".tif", ".tiff"}
@@ -0,0 +1,53 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareImage_Formats(t *testing.T) {
+	// Fixtures are losslessly encoded copies of createQuadrantImage
+	tests := []struct {
+		fixture string
+		format  string
+	}{
+		{fixture: "quadrants.webp", format: "webp"},
+		{fixture: "quadrants.bmp", format: "bmp"},
+		{fixture: "quadrants.tiff", format: "tiff"},
+	}
+
+	uploader := &Uploader{}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join("testdata", "fixtures", tt.fixture)
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+			_, format, err := image.DecodeConfig(file)
+			file.Close()
+			require.NoError(t, err)
+			assert.Equal(t, tt.format, format)
+
+			img, err := loadImage(context.Background(), path, Limits{}, nil)
+			require.NoError(t, err)
+			assertQuadrants(t, img, quadrantRed, quadrantGreen, quadrantBlue, quadrantWhite)
+
+			data, _, err := uploader.prepareImage(context.Background(), Options{ImagePath: path, ResizeMode: ResizeOriginal})
+			require.NoError(t, err)
+
+			result, format, err := image.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, "png", format)
+			assert.Equal(t, 64, result.Bounds().Dx())
+			assert.Equal(t, 32, result.Bounds().Dy())
+		})
+	}
+}
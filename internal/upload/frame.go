@@ -0,0 +1,300 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font/opentype"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// maxFrameBorder keeps the photo at least a fifth of the shorter side
+	maxFrameBorder = 0.4
+	// DefaultCaptionDateFormat is the Go layout of {date} in captions
+	DefaultCaptionDateFormat = "2006/01/02"
+)
+
+// DefaultCaptionLines are used for captions that don't list their lines
+var DefaultCaptionLines = []string{"{note}", "{world}  {date}"}
+
+// FrameTemplate describes a keepsake-style frame around the print: borders,
+// a background and a caption area. Frames are applied after the image is
+// fitted to the print size; the photo is scaled into the area inside the
+// borders, so the print keeps its size.
+type FrameTemplate struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Border sizes are fractions of the shorter side of the print
+	Border FrameBorder `yaml:"border" json:"border"`
+	// Color fills the frame in #RRGGBB notation; white if empty
+	Color string `yaml:"color" json:"color,omitempty"`
+	// BackgroundImage, if set, is scaled to cover the frame instead of Color
+	BackgroundImage string `yaml:"backgroundImage" json:"backgroundImage,omitempty"`
+	// Caption is rendered onto the frame; no caption if nil
+	Caption *FrameCaption `yaml:"caption" json:"caption,omitempty"`
+}
+
+// FrameBorder is the width of each side of a frame
+type FrameBorder struct {
+	Top    float64 `yaml:"top" json:"top"`
+	Right  float64 `yaml:"right" json:"right"`
+	Bottom float64 `yaml:"bottom" json:"bottom"`
+	Left   float64 `yaml:"left" json:"left"`
+}
+
+// FrameCaption is the text rendered onto a frame. Lines may contain the
+// placeholders {note}, {world} and {date}; lines that are empty after
+// replacing them are left out. The text is rendered with an embedded font
+// that covers Japanese.
+type FrameCaption struct {
+	// X, Y, Width and Height are the caption area normalized to the print
+	// size. If they are all zero the area is the bottom border, inside the
+	// left and right borders.
+	X      float64 `yaml:"x" json:"x,omitempty"`
+	Y      float64 `yaml:"y" json:"y,omitempty"`
+	Width  float64 `yaml:"width" json:"width,omitempty"`
+	Height float64 `yaml:"height" json:"height,omitempty"`
+	// Color is the text color in #RRGGBB notation; dark gray if empty
+	Color string `yaml:"color" json:"color,omitempty"`
+	// Align is "left", "center" or "right"; left if empty
+	Align TextAlign `yaml:"align" json:"align,omitempty"`
+	// Lines defaults to DefaultCaptionLines
+	Lines []string `yaml:"lines" json:"lines,omitempty"`
+	// DateFormat is the Go time layout for {date}; DefaultCaptionDateFormat
+	// if empty
+	DateFormat string `yaml:"dateFormat" json:"dateFormat,omitempty"`
+}
+
+// CaptionText holds the values for the caption placeholders
+type CaptionText struct {
+	Note  string
+	World string
+	// Date is left out of the caption if it is zero
+	Date time.Time
+}
+
+// ParseFrameTemplate decodes and validates a YAML frame template
+func ParseFrameTemplate(data []byte) (*FrameTemplate, error) {
+	var t FrameTemplate
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to parse frame template: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks the template without loading its background image
+func (t FrameTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("frame name is required")
+	}
+	for _, b := range []float64{t.Border.Top, t.Border.Right, t.Border.Bottom, t.Border.Left} {
+		if b < 0 || b > maxFrameBorder {
+			return fmt.Errorf("invalid frame border: %g (must be between 0 and %g)", b, maxFrameBorder)
+		}
+	}
+	if t.Color != "" {
+		if _, err := ParseHexColor(t.Color); err != nil {
+			return err
+		}
+	}
+	if t.Caption == nil {
+		return nil
+	}
+
+	c := t.Caption
+	if c.X < 0 || c.Y < 0 || c.Width < 0 || c.Height < 0 || c.X+c.Width > 1 || c.Y+c.Height > 1 {
+		return fmt.Errorf("invalid caption area: %.2f, %.2f %.2fx%.2f (must lie within 0-1)", c.X, c.Y, c.Width, c.Height)
+	}
+	if c.Color != "" {
+		if _, err := ParseHexColor(c.Color); err != nil {
+			return err
+		}
+	}
+	switch c.Align {
+	case "", AlignLeft, AlignCenter, AlignRight:
+	default:
+		return fmt.Errorf("invalid caption alignment: %s", c.Align)
+	}
+	return nil
+}
+
+// Filter validates the template and prepares a frame with the given
+// caption text. The frame is drawn around a print whose photo is already
+// fitted to the opening, as Upload does with Options.Frame; the photo
+// isn't scaled or cropped.
+func (t FrameTemplate) Filter(text CaptionText) (Filter, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	f := &frameFilter{
+		border: t.Border,
+		color:  color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+	}
+	if t.Color != "" {
+		f.color, _ = ParseHexColor(t.Color)
+	}
+	if t.BackgroundImage != "" {
+		bg, err := loadOverlayImage(t.BackgroundImage, "frame background")
+		if err != nil {
+			return nil, err
+		}
+		f.background = bg
+	}
+
+	if t.Caption != nil {
+		f.caption = t.Caption
+		f.lines = t.Caption.render(text)
+		f.textColor = color.NRGBA{R: 51, G: 51, B: 51, A: 255}
+		if t.Caption.Color != "" {
+			f.textColor, _ = ParseHexColor(t.Caption.Color)
+		}
+		if len(f.lines) > 0 {
+			font, err := mplusFont.load()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load caption font: %w", err)
+			}
+			f.font = font
+		}
+	}
+	return f, nil
+}
+
+// render replaces the placeholders in the caption lines
+func (c *FrameCaption) render(text CaptionText) []string {
+	date := ""
+	if !text.Date.IsZero() {
+		layout := c.DateFormat
+		if layout == "" {
+			layout = DefaultCaptionDateFormat
+		}
+		date = text.Date.Format(layout)
+	}
+	replacer := strings.NewReplacer("{note}", text.Note, "{world}", text.World, "{date}", date)
+
+	templates := c.Lines
+	if len(templates) == 0 {
+		templates = DefaultCaptionLines
+	}
+
+	var lines []string
+	for _, tmpl := range templates {
+		// Notes can span several lines
+		for _, line := range strings.Split(replacer.Replace(tmpl), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+// frameFilter draws a prepared frame around the photo in the opening of
+// the print
+type frameFilter struct {
+	border     FrameBorder
+	color      color.Color
+	background image.Image
+	caption    *FrameCaption
+	lines      []string
+	textColor  color.Color
+	font       *opentype.Font
+}
+
+func (f *frameFilter) Apply(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	inner := f.border.inner(width, height)
+	if inner.Empty() {
+		return img
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if f.background != nil {
+		draw.Draw(dst, dst.Bounds(), imaging.Fill(f.background, width, height, imaging.Center, imaging.Lanczos), image.Point{}, draw.Src)
+	} else {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(f.color), image.Point{}, draw.Src)
+	}
+
+	// The photo was already fitted to the opening by resizeImage
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+
+	if len(f.lines) > 0 {
+		f.drawCaption(dst, f.captionArea(dst.Bounds(), inner))
+	}
+	return dst
+}
+
+// inner returns the opening of the frame on a width x height print, in
+// pixels
+func (b FrameBorder) inner(width, height int) image.Rectangle {
+	short := float64(min(width, height))
+	px := func(fraction float64) int {
+		return int(math.Round(fraction * short))
+	}
+	return image.Rectangle{
+		Min: image.Pt(px(b.Left), px(b.Top)),
+		Max: image.Pt(width-px(b.Right), height-px(b.Bottom)),
+	}
+}
+
+// captionArea returns the caption area in pixels
+func (f *frameFilter) captionArea(bounds, inner image.Rectangle) image.Rectangle {
+	c := f.caption
+	if c.X == 0 && c.Y == 0 && c.Width == 0 && c.Height == 0 {
+		return image.Rect(inner.Min.X, inner.Max.Y, inner.Max.X, bounds.Max.Y)
+	}
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	return image.Rect(
+		int(math.Round(c.X*w)), int(math.Round(c.Y*h)),
+		int(math.Round((c.X+c.Width)*w)), int(math.Round((c.Y+c.Height)*h)),
+	)
+}
+
+// captionFill is the part of the caption area height taken by the text
+const captionFill = 0.8
+
+// drawCaption renders the caption lines as large as they fit in area, at
+// most captionFill/2 of its height per line, and centers them vertically
+func (f *frameFilter) drawCaption(dst draw.Image, area image.Rectangle) {
+	if area.Dx() <= 0 || area.Dy() <= 0 {
+		return
+	}
+
+	lineHeight := float64(area.Dy()) * captionFill / float64(max(len(f.lines), 2))
+	text := drawText(f.font, f.lines, lineHeight, f.textColor, f.caption.Align)
+	if text != nil && text.Bounds().Dx() > area.Dx() {
+		// Shrink long lines to the width of the area
+		lineHeight *= float64(area.Dx()) / float64(text.Bounds().Dx())
+		text = drawText(f.font, f.lines, lineHeight, f.textColor, f.caption.Align)
+	}
+	if text == nil {
+		return
+	}
+
+	size := text.Bounds().Size()
+	x := area.Min.X
+	switch f.caption.Align {
+	case AlignCenter:
+		x += (area.Dx() - size.X) / 2
+	case AlignRight:
+		x = area.Max.X - size.X
+	}
+	y := area.Min.Y + (area.Dy()-size.Y)/2
+	r := image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x+size.X, y+size.Y)}.Intersect(area)
+	draw.Draw(dst, r, text, text.Bounds().Min.Add(r.Min.Sub(image.Pt(x, y))), draw.Over)
+}
@@ -0,0 +1,287 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFrameTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		expected    *FrameTemplate
+		expectError string
+	}{
+		{
+			name: "Full template",
+			yaml: `
+name: Polaroid
+description: White border
+border: {top: 0.04, right: 0.04, bottom: 0.2, left: 0.04}
+color: "#ffffff"
+backgroundImage: paper.png
+caption:
+  x: 0.1
+  y: 0.8
+  width: 0.8
+  height: 0.15
+  color: "#333333"
+  align: center
+  lines: ["{note}", "{date}"]
+  dateFormat: "2006-01-02"
+`,
+			expected: &FrameTemplate{
+				Name:            "Polaroid",
+				Description:     "White border",
+				Border:          FrameBorder{Top: 0.04, Right: 0.04, Bottom: 0.2, Left: 0.04},
+				Color:           "#ffffff",
+				BackgroundImage: "paper.png",
+				Caption: &FrameCaption{
+					X: 0.1, Y: 0.8, Width: 0.8, Height: 0.15,
+					Color:      "#333333",
+					Align:      AlignCenter,
+					Lines:      []string{"{note}", "{date}"},
+					DateFormat: "2006-01-02",
+				},
+			},
+		},
+		{
+			name:     "Border only",
+			yaml:     "name: Thin\nborder: {top: 0.01, right: 0.01, bottom: 0.01, left: 0.01}\n",
+			expected: &FrameTemplate{Name: "Thin", Border: FrameBorder{Top: 0.01, Right: 0.01, Bottom: 0.01, Left: 0.01}},
+		},
+		{
+			name:        "Unknown field",
+			yaml:        "name: Typo\nborders: {top: 0.1}\n",
+			expectError: "field borders not found",
+		},
+		{
+			name:        "Missing name",
+			yaml:        "border: {top: 0.1}\n",
+			expectError: "frame name is required",
+		},
+		{
+			name:        "Border too wide",
+			yaml:        "name: Wide\nborder: {bottom: 0.5}\n",
+			expectError: "invalid frame border",
+		},
+		{
+			name:        "Invalid color",
+			yaml:        "name: Red\ncolor: red\n",
+			expectError: "invalid color",
+		},
+		{
+			name:        "Caption outside the print",
+			yaml:        "name: Off\ncaption: {x: 0.5, width: 0.6}\n",
+			expectError: "invalid caption area",
+		},
+		{
+			name:        "Invalid alignment",
+			yaml:        "name: Off\ncaption: {align: justify}\n",
+			expectError: "invalid caption alignment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := ParseFrameTemplate([]byte(tt.yaml))
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, template)
+		})
+	}
+}
+
+func TestFrameCaption_Render(t *testing.T) {
+	date := time.Date(2024, 3, 15, 21, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		caption  FrameCaption
+		text     CaptionText
+		expected []string
+	}{
+		{
+			name:     "Default lines",
+			text:     CaptionText{Note: "思い出", World: "The Black Cat", Date: date},
+			expected: []string{"思い出", "The Black Cat  2024/03/15"},
+		},
+		{
+			name:     "Empty lines are left out",
+			text:     CaptionText{World: "ワールド"},
+			expected: []string{"ワールド"},
+		},
+		{
+			name:     "Multi-line note",
+			caption:  FrameCaption{Lines: []string{"{note}"}},
+			text:     CaptionText{Note: "一行目\n二行目"},
+			expected: []string{"一行目", "二行目"},
+		},
+		{
+			name:     "Date format",
+			caption:  FrameCaption{Lines: []string{"{date}"}, DateFormat: "Jan 2, 2006"},
+			text:     CaptionText{Date: date},
+			expected: []string{"Mar 15, 2024"},
+		},
+		{
+			name:    "Nothing to show",
+			caption: FrameCaption{Lines: []string{"{note}", "{date}"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.caption.render(tt.text))
+		})
+	}
+}
+
+func TestFrame_Golden(t *testing.T) {
+	text := CaptionText{
+		Note:  "みんなで記念撮影 📷 Photo",
+		World: "Japan Street 日本の街",
+		Date:  time.Date(2024, 3, 15, 21, 30, 0, 0, time.UTC),
+	}
+
+	polaroid := FrameTemplate{
+		Name:    "polaroid",
+		Border:  FrameBorder{Top: 0.04, Right: 0.04, Bottom: 0.2, Left: 0.04},
+		Color:   "#fbfaf5",
+		Caption: &FrameCaption{},
+	}
+
+	// The photo is fitted to the opening like to a print without a frame;
+	// ResizeOriginal keeps the print size and fills the opening
+	tests := []struct {
+		name          string
+		template      FrameTemplate
+		width, height int
+		opts          Options
+	}{
+		{
+			name:     "frame_polaroid",
+			template: polaroid,
+			width:    640, height: 360,
+			opts: Options{ResizeMode: ResizeOriginal},
+		},
+		{
+			name: "frame_caption_area_right",
+			template: FrameTemplate{
+				Name:   "side",
+				Border: FrameBorder{Top: 0.05, Right: 0.4, Bottom: 0.05, Left: 0.05},
+				Color:  "#202830",
+				Caption: &FrameCaption{
+					X: 0.79, Y: 0.1, Width: 0.19, Height: 0.8,
+					Color: "#f0f0f0",
+					Align: AlignRight,
+					Lines: []string{"{world}", "{note}", "{date}"},
+				},
+			},
+			width: 640, height: 360,
+			opts: Options{ResizeMode: ResizeOriginal},
+		},
+		{
+			name: "frame_border_only",
+			template: FrameTemplate{
+				Name:   "simple",
+				Border: FrameBorder{Top: 0.03, Right: 0.03, Bottom: 0.03, Left: 0.03},
+				Color:  "#111111",
+			},
+			width: 640, height: 360,
+			opts: Options{ResizeMode: ResizeOriginal},
+		},
+		{
+			name:     "frame_polaroid_fit",
+			template: polaroid,
+			width:    400, height: 300,
+			opts: Options{ResizeMode: ResizeFit, PadColor: color.NRGBA{R: 32, G: 32, B: 64, A: 255}},
+		},
+		{
+			name:     "frame_polaroid_focal_left",
+			template: polaroid,
+			width:    840, height: 360,
+			opts: Options{ResizeMode: ResizeFill, CropAnchor: CropFocal, FocalPoint: FocalPoint{X: 0.2, Y: 0.5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.template.Filter(text)
+			require.NoError(t, err)
+
+			opts := tt.opts
+			opts.Frame = &tt.template
+			img := createPatternImage(tt.width, tt.height)
+			fitted := resizeImage(img, opts)
+			result := f.Apply(fitted)
+			assert.Equal(t, fitted.Bounds(), result.Bounds())
+			assertGolden(t, tt.name, result)
+		})
+	}
+}
+
+func TestFrame_BackgroundImage(t *testing.T) {
+	dir := t.TempDir()
+	bgPath := filepath.Join(dir, "paper.png")
+	paper := color.NRGBA{R: 230, G: 210, B: 170, A: 255}
+	require.NoError(t, writePNG(bgPath, paintImage(image.NewNRGBA(image.Rect(0, 0, 50, 50)), paper)))
+
+	f, err := FrameTemplate{Name: "paper", Border: FrameBorder{Top: 0.1, Right: 0.1, Bottom: 0.1, Left: 0.1}, BackgroundImage: bgPath}.Filter(CaptionText{})
+	require.NoError(t, err)
+
+	opts := Options{ResizeMode: ResizeOriginal, Frame: &FrameTemplate{Border: FrameBorder{Top: 0.1, Right: 0.1, Bottom: 0.1, Left: 0.1}}}
+	result := f.Apply(resizeImage(createPatternImage(200, 100), opts))
+	assert.Equal(t, paper, color.NRGBAModel.Convert(result.At(2, 2)))
+	assert.NotEqual(t, paper, color.NRGBAModel.Convert(result.At(50, 50)))
+
+	_, err = FrameTemplate{Name: "missing", BackgroundImage: filepath.Join(dir, "missing.png")}.Filter(CaptionText{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open frame background")
+}
+
+func TestPrepareImage_Frame(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "test.png")
+	require.NoError(t, createTestImage(imagePath, "png", 400, 300))
+	modTime := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+	require.NoError(t, os.Chtimes(imagePath, modTime, modTime))
+
+	uploader := &Uploader{}
+
+	// The frame keeps the print size
+	data, _, err := uploader.prepareImage(context.Background(), Options{
+		ImagePath:  imagePath,
+		Note:       "メモ",
+		ResizeMode: ResizeFit,
+		Frame: &FrameTemplate{
+			Name:    "polaroid",
+			Border:  FrameBorder{Top: 0.04, Right: 0.04, Bottom: 0.2, Left: 0.04},
+			Caption: &FrameCaption{},
+		},
+	})
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, Print1080pWidth, Print1080pHeight), img.Bounds())
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, color.NRGBAModel.Convert(img.At(10, 10)))
+
+	_, _, err = uploader.prepareImage(context.Background(), Options{
+		ImagePath: imagePath,
+		Frame:     &FrameTemplate{Name: "broken", Border: FrameBorder{Left: 0.9}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid frame border")
+}
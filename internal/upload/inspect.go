@@ -0,0 +1,215 @@
+package upload
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+)
+
+// ImageInfo describes an image file without decoding its pixels
+type ImageInfo struct {
+	// Format is the format found by sniffing the content, e.g. "png" or
+	// "jpeg", regardless of the file extension
+	Format string
+	// Width and Height are the displayed dimensions, after EXIF orientation
+	Width  int
+	Height int
+	// FileSize is the size of the file in bytes
+	FileSize int64
+	// ColorModel names the decoder's color model, e.g. "RGBA" or "YCbCr"
+	ColorModel string
+	// Frames is the number of frames in an animated GIF and 1 otherwise.
+	// Only the first frame is uploaded.
+	Frames int
+	// Orientation is the EXIF orientation (1-8) of JPEGs and 1 otherwise
+	Orientation int
+	// OutputWidth and OutputHeight are the dimensions of the uploaded PNG
+	// with the resize mode, manual rotation and filters in opts
+	OutputWidth  int
+	OutputHeight int
+}
+
+// Inspect sniffs the format and dimensions of the image at opts.ImagePath
+// with image.DecodeConfig and predicts the size of the image Upload would
+// send. It returns an error for files that aren't supported images and an
+// ImageTooLargeError for images exceeding opts.Limits.
+func Inspect(opts Options) (*ImageInfo, error) {
+	if err := validateProcessing(opts); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(opts.ImagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat image file: %w", err)
+	}
+
+	cfg, format, err := image.DecodeConfig(bufio.NewReader(file))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, fmt.Errorf("unsupported image format")
+		}
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if err := opts.Limits.check(cfg); err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		Format:      format,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		FileSize:    stat.Size(),
+		ColorModel:  colorModelName(cfg.ColorModel),
+		Frames:      1,
+		Orientation: 1,
+	}
+
+	switch format {
+	case "jpeg":
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read image file: %w", err)
+		}
+		info.Orientation = readEXIFOrientation(file)
+		if info.Orientation >= 5 {
+			// Orientations 5-8 are transposed
+			info.Width, info.Height = info.Height, info.Width
+		}
+	case "gif":
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read image file: %w", err)
+		}
+		frames, err := gifFrameCount(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GIF frames: %w", err)
+		}
+		info.Frames = frames
+	}
+
+	if err := checkFilterSize(opts, info.Width, info.Height); err != nil {
+		return nil, err
+	}
+
+	width, height := info.Width, info.Height
+	if opts.Rotate == Rotate90 || opts.Rotate == Rotate270 {
+		width, height = height, width
+	}
+	width, height = Pipeline(opts.Filters).Size(width, height)
+	info.OutputWidth, info.OutputHeight = outputSize(width, height, opts.ResizeMode)
+
+	return info, nil
+}
+
+// colorModelName returns a short name for the standard library color models
+func colorModelName(model color.Model) string {
+	switch model {
+	case color.RGBAModel:
+		return "RGBA"
+	case color.RGBA64Model:
+		return "RGBA64"
+	case color.NRGBAModel:
+		return "NRGBA"
+	case color.NRGBA64Model:
+		return "NRGBA64"
+	case color.AlphaModel:
+		return "Alpha"
+	case color.Alpha16Model:
+		return "Alpha16"
+	case color.GrayModel:
+		return "Gray"
+	case color.Gray16Model:
+		return "Gray16"
+	case color.YCbCrModel:
+		return "YCbCr"
+	case color.NYCbCrAModel:
+		return "NYCbCrA"
+	case color.CMYKModel:
+		return "CMYK"
+	}
+	if _, ok := model.(color.Palette); ok {
+		return "Paletted"
+	}
+	return "Unknown"
+}
+
+// gifFrameCount counts the image descriptors in a GIF without decoding the
+// frames
+func gifFrameCount(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+
+	// Header and logical screen descriptor
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return 0, err
+	}
+	if flags := header[10]; flags&0x80 != 0 {
+		if _, err := br.Discard(3 << ((flags & 0x07) + 1)); err != nil {
+			return 0, err
+		}
+	}
+
+	frames := 0
+	for {
+		introducer, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch introducer {
+		case 0x21: // Extension
+			if _, err := br.ReadByte(); err != nil {
+				return 0, err
+			}
+			if err := skipGIFSubBlocks(br); err != nil {
+				return 0, err
+			}
+		case 0x2c: // Image descriptor
+			frames++
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(br, descriptor); err != nil {
+				return 0, err
+			}
+			if flags := descriptor[8]; flags&0x80 != 0 {
+				if _, err := br.Discard(3 << ((flags & 0x07) + 1)); err != nil {
+					return 0, err
+				}
+			}
+			// LZW minimum code size, then the image data
+			if _, err := br.ReadByte(); err != nil {
+				return 0, err
+			}
+			if err := skipGIFSubBlocks(br); err != nil {
+				return 0, err
+			}
+		case 0x3b: // Trailer
+			return frames, nil
+		default:
+			return 0, fmt.Errorf("unexpected block introducer 0x%02x", introducer)
+		}
+	}
+}
+
+// skipGIFSubBlocks skips data sub-blocks up to the block terminator
+func skipGIFSubBlocks(br *bufio.Reader) error {
+	for {
+		size, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := br.Discard(int(size)); err != nil {
+			return err
+		}
+	}
+}
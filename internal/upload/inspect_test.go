@@ -0,0 +1,189 @@
+package upload
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		create      func(path string) error
+		fileName    string
+		opts        Options
+		expectError string
+		check       func(*testing.T, *ImageInfo)
+	}{
+		{
+			name:     "PNG",
+			fileName: "landscape.png",
+			create: func(path string) error {
+				return createTestImage(path, "png", 1000, 800)
+			},
+			opts: Options{ResizeMode: ResizeStretch},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, "png", info.Format)
+				assert.Equal(t, 1000, info.Width)
+				assert.Equal(t, 800, info.Height)
+				assert.Equal(t, "RGBA", info.ColorModel)
+				assert.Equal(t, 1, info.Frames)
+				assert.Equal(t, 1920, info.OutputWidth)
+				assert.Equal(t, 1080, info.OutputHeight)
+			},
+		},
+		{
+			name:     "Upper-case extension",
+			fileName: "UPPER.PNG",
+			create: func(path string) error {
+				return createTestImage(path, "png", 300, 200)
+			},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, "png", info.Format)
+			},
+		},
+		{
+			name:     "JPEG with a misleading extension",
+			fileName: "photo.png",
+			create: func(path string) error {
+				return createTestImage(path, "jpeg", 300, 200)
+			},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, "jpeg", info.Format)
+				assert.Equal(t, "YCbCr", info.ColorModel)
+			},
+		},
+		{
+			name:     "Original size is limited",
+			fileName: "large.png",
+			create: func(path string) error {
+				return createTestImage(path, "png", 3000, 1000)
+			},
+			opts: Options{ResizeMode: ResizeOriginal},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, 2048, info.OutputWidth)
+				assert.Equal(t, 683, info.OutputHeight)
+			},
+		},
+		{
+			name:     "Manual rotation",
+			fileName: "rotated.png",
+			create: func(path string) error {
+				return createTestImage(path, "png", 1000, 800)
+			},
+			opts: Options{ResizeMode: ResizeFit, Rotate: Rotate90},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, 1000, info.Width)
+				assert.Equal(t, 1080, info.OutputWidth)
+				assert.Equal(t, 1920, info.OutputHeight)
+			},
+		},
+		{
+			name:     "Filters",
+			fileName: "filtered.png",
+			create: func(path string) error {
+				return createTestImage(path, "png", 1000, 800)
+			},
+			opts: Options{
+				ResizeMode: ResizeOriginal,
+				Filters:    []Filter{CropFilter{X: 0.1, Y: 0.1, Width: 0.5, Height: 0.5}, ResizeFilter{Width: 250}},
+			},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, 1000, info.Width)
+				assert.Equal(t, 250, info.OutputWidth)
+				assert.Equal(t, 200, info.OutputHeight)
+			},
+		},
+		{
+			name:     "EXIF orientation",
+			fileName: "portrait.jpg",
+			create: func(path string) error {
+				return writeJPEGWithOrientation(path, createQuadrantImage(), 6)
+			},
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, 6, info.Orientation)
+				assert.Equal(t, 32, info.Width)
+				assert.Equal(t, 64, info.Height)
+				assert.Equal(t, 1080, info.OutputWidth)
+				assert.Equal(t, 1920, info.OutputHeight)
+			},
+		},
+		{
+			name:     "Animated GIF",
+			fileName: "animated.gif",
+			create:   createAnimatedGIF,
+			check: func(t *testing.T, info *ImageInfo) {
+				assert.Equal(t, "gif", info.Format)
+				assert.Equal(t, "Paletted", info.ColorModel)
+				assert.Equal(t, 3, info.Frames)
+			},
+		},
+		{
+			name:     "Text file renamed to PNG",
+			fileName: "notes.png",
+			create: func(path string) error {
+				return os.WriteFile(path, []byte("this is not an image"), 0644)
+			},
+			expectError: "unsupported image format",
+		},
+		{
+			name:        "Missing file",
+			fileName:    "missing.png",
+			create:      func(string) error { return nil },
+			expectError: "failed to open image file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.fileName)
+			require.NoError(t, tt.create(path))
+
+			opts := tt.opts
+			opts.ImagePath = path
+			info, err := Inspect(opts)
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+
+			require.NoError(t, err)
+			stat, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, stat.Size(), info.FileSize)
+			tt.check(t, info)
+		})
+	}
+}
+
+// createAnimatedGIF writes a three-frame GIF
+func createAnimatedGIF(path string) error {
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 40, 30), palette.Plan9)
+		for p := range frame.Pix {
+			frame.Pix[p] = uint8(i * 40)
+		}
+		frame.SetColorIndex(i, i, uint8(frame.Palette.Index(color.White)))
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gif.EncodeAll(file, anim)
+}
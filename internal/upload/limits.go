@@ -0,0 +1,95 @@
+package upload
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+const (
+	// DefaultMaxPixels allows 8K screenshots with plenty of headroom
+	DefaultMaxPixels = 64 * 1024 * 1024
+	// DefaultMaxMemory is the default ceiling for the estimated memory
+	// needed to decode and process an image
+	DefaultMaxMemory = 1024 * 1024 * 1024 // 1GB
+
+	// processingBytesPerPixel accounts for the NRGBA working copy that
+	// resizing and the other image operations make
+	processingBytesPerPixel = 4
+)
+
+// Limits caps the dimensions of images that are decoded, so that a small
+// file declaring huge dimensions can't exhaust memory. Zero values select
+// the defaults.
+type Limits struct {
+	// MaxPixels is the largest width * height that is decoded
+	MaxPixels int64
+	// MaxMemory is the largest estimated memory, in bytes, for decoding and
+	// processing an image
+	MaxMemory int64
+}
+
+// ImageTooLargeError is returned before decoding an image whose declared
+// dimensions exceed the Limits
+type ImageTooLargeError struct {
+	Width  int
+	Height int
+	// Memory is the estimated memory needed to decode and process the image
+	Memory    int64
+	MaxPixels int64
+	MaxMemory int64
+}
+
+func (e *ImageTooLargeError) Error() string {
+	if pixels := int64(e.Width) * int64(e.Height); pixels > e.MaxPixels {
+		return fmt.Sprintf("image too large: %dx%d is %d pixels (max: %d pixels)", e.Width, e.Height, pixels, e.MaxPixels)
+	}
+	return fmt.Sprintf("image too large: %dx%d needs about %d MB to decode (max: %d MB)",
+		e.Width, e.Height, e.Memory/(1024*1024), e.MaxMemory/(1024*1024))
+}
+
+// check returns an ImageTooLargeError if an image with the given header
+// exceeds the limits
+func (l Limits) check(cfg image.Config) error {
+	maxPixels := l.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	maxMemory := l.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = DefaultMaxMemory
+	}
+
+	pixels := int64(cfg.Width) * int64(cfg.Height)
+	memory := pixels * (bytesPerPixel(cfg.ColorModel) + processingBytesPerPixel)
+
+	if cfg.Width < 0 || cfg.Height < 0 || pixels > maxPixels || memory > maxMemory {
+		return &ImageTooLargeError{
+			Width:     cfg.Width,
+			Height:    cfg.Height,
+			Memory:    memory,
+			MaxPixels: maxPixels,
+			MaxMemory: maxMemory,
+		}
+	}
+	return nil
+}
+
+// bytesPerPixel estimates the size of a pixel decoded with the color model
+func bytesPerPixel(model color.Model) int64 {
+	switch model {
+	case color.GrayModel, color.AlphaModel:
+		return 1
+	case color.Gray16Model, color.Alpha16Model:
+		return 2
+	case color.YCbCrModel:
+		// Worst case: no chroma subsampling
+		return 3
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	}
+	if _, ok := model.(color.Palette); ok {
+		return 1
+	}
+	return 4
+}
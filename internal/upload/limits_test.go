@@ -0,0 +1,146 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_Check(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      Limits
+		cfg         image.Config
+		expectError string
+	}{
+		{
+			name: "8K screenshot within defaults",
+			cfg:  image.Config{Width: 7680, Height: 4320, ColorModel: color.RGBAModel},
+		},
+		{
+			name:        "Too many pixels",
+			cfg:         image.Config{Width: 50000, Height: 50000, ColorModel: color.GrayModel},
+			expectError: "2500000000 pixels",
+		},
+		{
+			name:        "Too much memory for 16-bit color",
+			limits:      Limits{MaxMemory: 100 * 1024 * 1024},
+			cfg:         image.Config{Width: 4000, Height: 3000, ColorModel: color.NRGBA64Model},
+			expectError: "needs about 137 MB to decode (max: 100 MB)",
+		},
+		{
+			name:        "Custom pixel limit",
+			limits:      Limits{MaxPixels: 1000},
+			cfg:         image.Config{Width: 40, Height: 30, ColorModel: color.RGBAModel},
+			expectError: "1200 pixels (max: 1000 pixels)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.check(tt.cfg)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var tooLargeErr *ImageTooLargeError
+			require.True(t, errors.As(err, &tooLargeErr))
+			assert.Equal(t, tt.cfg.Width, tooLargeErr.Width)
+			assert.Equal(t, tt.cfg.Height, tooLargeErr.Height)
+			assert.Contains(t, err.Error(), tt.expectError)
+		})
+	}
+}
+
+func TestPrepareImage_DecompressionBomb(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bomb.png")
+	require.NoError(t, os.WriteFile(path, pngHeaderOnly(50000, 50000), 0644))
+
+	uploader := &Uploader{}
+	_, _, err := uploader.prepareImage(context.Background(), Options{ImagePath: path})
+
+	var tooLargeErr *ImageTooLargeError
+	require.True(t, errors.As(err, &tooLargeErr), "unexpected error: %v", err)
+	assert.Equal(t, 50000, tooLargeErr.Width)
+
+	_, err = Inspect(Options{ImagePath: path})
+	assert.True(t, errors.As(err, &tooLargeErr))
+}
+
+func TestPrepareImage_CustomLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.png")
+	require.NoError(t, createTestImage(path, "png", 100, 100))
+
+	uploader := &Uploader{}
+	_, _, err := uploader.prepareImage(context.Background(), Options{ImagePath: path, Limits: Limits{MaxPixels: 5000}})
+	var tooLargeErr *ImageTooLargeError
+	assert.True(t, errors.As(err, &tooLargeErr))
+
+	_, _, err = uploader.prepareImage(context.Background(), Options{ImagePath: path, Limits: Limits{MaxPixels: 10000}})
+	assert.NoError(t, err)
+}
+
+func TestPrepareImage_FilterLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.png")
+	require.NoError(t, createTestImage(path, "png", 100, 100))
+
+	tests := []struct {
+		name    string
+		filters []Filter
+	}{
+		{name: "Huge resize", filters: []Filter{ResizeFilter{Width: 100000, Height: 100000}}},
+		{name: "Resize up then down", filters: []Filter{ResizeFilter{Width: 100000}, ResizeFilter{Width: 100}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{ImagePath: path, Filters: tt.filters}
+
+			_, _, err := (&Uploader{}).prepareImage(context.Background(), opts)
+			var tooLargeErr *ImageTooLargeError
+			require.True(t, errors.As(err, &tooLargeErr), "unexpected error: %v", err)
+			assert.Equal(t, 100000, tooLargeErr.Width)
+			assert.Contains(t, err.Error(), "filter 1")
+
+			_, err = Inspect(opts)
+			assert.True(t, errors.As(err, &tooLargeErr))
+			_, err = PreviewCrop(context.Background(), opts, 320)
+			assert.True(t, errors.As(err, &tooLargeErr))
+		})
+	}
+}
+
+// pngHeaderOnly returns a PNG that declares the given dimensions but has no
+// image data
+func pngHeaderOnly(width, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+
+	writeChunk := func(typ string, data []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		chunk := append([]byte(typ), data...)
+		buf.Write(chunk)
+		binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // Bit depth
+	ihdr[9] = 6 // RGBA
+	writeChunk("IHDR", ihdr)
+	writeChunk("IEND", nil)
+
+	return buf.Bytes()
+}
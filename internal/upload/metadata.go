@@ -0,0 +1,356 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// PNG text keywords of the print metadata. Comment, Creation Time, Author
+// and Software are standard PNG keywords.
+const (
+	KeywordNote        = "Comment"
+	KeywordWorldID     = "VRChat World ID"
+	KeywordWorldName   = "VRChat World Name"
+	KeywordCaptureTime = "Creation Time"
+	KeywordSourceFile  = "Source File"
+	KeywordAuthor      = "Author"
+	KeywordSoftware    = "Software"
+)
+
+// maxPNGTextChunkSize skips text chunks that are unreasonably large
+const maxPNGTextChunkSize = 1 << 20
+
+// Metadata records where a print came from. It is written into the PNG
+// as text chunks; empty fields are left out.
+type Metadata struct {
+	Note        string    `json:"note,omitempty"`
+	WorldID     string    `json:"worldId,omitempty"`
+	WorldName   string    `json:"worldName,omitempty"`
+	CaptureTime time.Time `json:"captureTime,omitempty"`
+	// SourceFile is the name of the original image file, without its
+	// directory
+	SourceFile string `json:"sourceFile,omitempty"`
+	Author     string `json:"author,omitempty"`
+	// Software names the tool and its version
+	Software string `json:"software,omitempty"`
+}
+
+// MetadataField names a metadata field, for leaving it out of prints
+type MetadataField string
+
+const (
+	MetadataNote        MetadataField = "note"
+	MetadataWorld       MetadataField = "world"
+	MetadataCaptureTime MetadataField = "captureTime"
+	MetadataSourceFile  MetadataField = "sourceFile"
+	MetadataAuthor      MetadataField = "author"
+	MetadataSoftware    MetadataField = "software"
+)
+
+// ParseMetadataFields converts field names, as used in the config file
+func ParseMetadataFields(names []string) ([]MetadataField, error) {
+	fields := make([]MetadataField, 0, len(names))
+	for _, name := range names {
+		switch f := MetadataField(name); f {
+		case MetadataNote, MetadataWorld, MetadataCaptureTime, MetadataSourceFile, MetadataAuthor, MetadataSoftware:
+			fields = append(fields, f)
+		default:
+			return nil, fmt.Errorf("unknown metadata field: %s", name)
+		}
+	}
+	return fields, nil
+}
+
+// MetadataOptions controls the metadata Upload embeds in the PNG. The note,
+// world, capture time and source file come from Options; the zero value
+// embeds all of them.
+type MetadataOptions struct {
+	// Disabled leaves the PNG without metadata
+	Disabled bool
+	// Author and Software are written as given
+	Author   string
+	Software string
+	// Omit lists fields to leave out
+	Omit []MetadataField
+}
+
+// metadata returns the metadata to embed for opts, or nil if disabled
+func (opts Options) metadata() *Metadata {
+	if opts.Metadata.Disabled {
+		return nil
+	}
+
+	m := &Metadata{
+		Note:        opts.Note,
+		WorldID:     opts.WorldID,
+		WorldName:   opts.WorldName,
+		CaptureTime: captureTime(opts),
+		SourceFile:  filepath.Base(opts.ImagePath),
+		Author:      opts.Metadata.Author,
+		Software:    opts.Metadata.Software,
+	}
+	for _, f := range opts.Metadata.Omit {
+		switch f {
+		case MetadataNote:
+			m.Note = ""
+		case MetadataWorld:
+			m.WorldID, m.WorldName = "", ""
+		case MetadataCaptureTime:
+			m.CaptureTime = time.Time{}
+		case MetadataSourceFile:
+			m.SourceFile = ""
+		case MetadataAuthor:
+			m.Author = ""
+		case MetadataSoftware:
+			m.Software = ""
+		}
+	}
+	return m
+}
+
+// captureTime returns when the image at opts.ImagePath was taken:
+// opts.CaptureTime if set, otherwise the time embedded in the file or its
+// VRChat filename, otherwise the modification time of the file, or the zero
+// time if unknown
+func captureTime(opts Options) time.Time {
+	if !opts.CaptureTime.IsZero() {
+		return opts.CaptureTime
+	}
+	if m, err := ExtractMetadata(opts.ImagePath); err == nil && !m.CaptureTime.IsZero() {
+		return m.CaptureTime
+	}
+	return modTime(opts.ImagePath)
+}
+
+// modTime returns the modification time of a file, or the zero time if it
+// can't be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// chunks encodes the metadata as PNG text chunks: tEXt for ASCII values and
+// uncompressed iTXt for everything else
+func (m *Metadata) chunks() []byte {
+	if m == nil {
+		return nil
+	}
+
+	var captured string
+	if !m.CaptureTime.IsZero() {
+		captured = m.CaptureTime.Format(time.RFC3339)
+	}
+
+	var buf bytes.Buffer
+	for _, field := range []struct{ keyword, value string }{
+		{KeywordNote, m.Note},
+		{KeywordWorldID, m.WorldID},
+		{KeywordWorldName, m.WorldName},
+		{KeywordCaptureTime, captured},
+		{KeywordSourceFile, m.SourceFile},
+		{KeywordAuthor, m.Author},
+		{KeywordSoftware, m.Software},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if isASCII(field.value) {
+			writePNGChunk(&buf, "tEXt", []byte(field.keyword+"\x00"+field.value))
+		} else {
+			// Keyword, no compression, empty language tag and translated
+			// keyword, then the UTF-8 text
+			writePNGChunk(&buf, "iTXt", []byte(field.keyword+"\x00\x00\x00\x00\x00"+field.value))
+		}
+	}
+	return buf.Bytes()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func writePNGChunk(w *bytes.Buffer, chunkType string, data []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	io.MultiWriter(w, crc).Write(append([]byte(chunkType), data...))
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// pngHeaderSize is the size of the PNG signature and the IHDR chunk
+const pngHeaderSize = 8 + 4 + 4 + 13 + 4
+
+// embedChunks inserts encoded chunks after the IHDR chunk of a PNG written
+// by image/png
+func embedChunks(data, chunks []byte) []byte {
+	if len(chunks) == 0 || len(data) < pngHeaderSize {
+		return data
+	}
+	out := make([]byte, 0, len(data)+len(chunks))
+	out = append(out, data[:pngHeaderSize]...)
+	out = append(out, chunks...)
+	return append(out, data[pngHeaderSize:]...)
+}
+
+// ReadMetadata reads the print metadata from the text chunks of a PNG.
+// Fields that aren't present are left empty.
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	text, err := readPNGText(r)
+	if err != nil {
+		return nil, err
+	}
+	return metadataFromText(text), nil
+}
+
+// metadataFromText maps PNG text chunks to metadata by keyword
+func metadataFromText(text map[string]string) *Metadata {
+	m := &Metadata{
+		Note:       text[KeywordNote],
+		WorldID:    text[KeywordWorldID],
+		WorldName:  text[KeywordWorldName],
+		SourceFile: text[KeywordSourceFile],
+		Author:     text[KeywordAuthor],
+		Software:   text[KeywordSoftware],
+	}
+	if s := text[KeywordCaptureTime]; s != "" {
+		// The PNG specification suggests RFC 1123 dates
+		m.CaptureTime = parseTime(s, time.RFC3339, time.RFC1123Z, time.RFC1123)
+	}
+	return m
+}
+
+// parseTime parses s with the first layout that matches, or returns the
+// zero time
+func parseTime(s string, layouts ...string) time.Time {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// readPNGText returns the tEXt, zTXt and iTXt chunks of a PNG as UTF-8
+// text by keyword. Chunks that can't be decoded are skipped.
+func readPNGText(r io.Reader) (map[string]string, error) {
+	br := bufio.NewReader(r)
+
+	signature := make([]byte, 8)
+	if _, err := io.ReadFull(br, signature); err != nil || string(signature) != "\x89PNG\r\n\x1a\n" {
+		return nil, errors.New("not a PNG file")
+	}
+
+	text := map[string]string{}
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return nil, fmt.Errorf("failed to read PNG chunk: %w", err)
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+
+		if chunkType == "IEND" {
+			return text, nil
+		}
+		if (chunkType != "tEXt" && chunkType != "zTXt" && chunkType != "iTXt") || length > maxPNGTextChunkSize {
+			if _, err := io.CopyN(io.Discard, br, length+4); err != nil {
+				return nil, fmt.Errorf("failed to read PNG chunk: %w", err)
+			}
+			continue
+		}
+
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("failed to read PNG chunk: %w", err)
+		}
+		if keyword, value, ok := decodeTextChunk(chunkType, data[:length]); ok {
+			text[keyword] = value
+		}
+	}
+}
+
+// decodeTextChunk decodes the keyword and text of a text chunk
+func decodeTextChunk(chunkType string, data []byte) (string, string, bool) {
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(keyword) == 0 {
+		return "", "", false
+	}
+
+	switch chunkType {
+	case "tEXt":
+		return latin1(keyword), latin1(rest), true
+	case "zTXt":
+		if len(rest) < 1 || rest[0] != 0 {
+			return "", "", false
+		}
+		value, err := inflate(rest[1:])
+		if err != nil {
+			return "", "", false
+		}
+		return latin1(keyword), latin1(value), true
+	case "iTXt":
+		if len(rest) < 2 {
+			return "", "", false
+		}
+		compressed, method := rest[0] == 1, rest[1]
+		// Skip the language tag and translated keyword
+		_, rest, ok = bytes.Cut(rest[2:], []byte{0})
+		if !ok {
+			return "", "", false
+		}
+		_, value, ok := bytes.Cut(rest, []byte{0})
+		if !ok {
+			return "", "", false
+		}
+		if compressed {
+			if method != 0 {
+				return "", "", false
+			}
+			var err error
+			if value, err = inflate(value); err != nil {
+				return "", "", false
+			}
+		}
+		if !utf8.Valid(value) {
+			return "", "", false
+		}
+		return latin1(keyword), string(value), true
+	}
+	return "", "", false
+}
+
+// latin1 converts ISO 8859-1 text to UTF-8
+func latin1(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, maxPNGTextChunkSize))
+}
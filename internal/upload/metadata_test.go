@@ -0,0 +1,207 @@
+package upload
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata Metadata
+	}{
+		{
+			name: "ASCII",
+			metadata: Metadata{
+				Note:        "Group photo",
+				WorldID:     "wrld_12345678-1234-1234-1234-123456789012",
+				WorldName:   "The Black Cat",
+				CaptureTime: time.Date(2024, 3, 15, 21, 30, 5, 0, time.FixedZone("JST", 9*60*60)),
+				SourceFile:  "VRChat_2024-03-15_21-30-05.123_1920x1080.png",
+				Author:      "yoshiken",
+				Software:    "vrc-print-upload/1.0.0",
+			},
+		},
+		{
+			name: "Japanese",
+			metadata: Metadata{
+				Note:      "みんなで記念撮影\n二行目",
+				WorldName: "日本の街",
+				Author:    "よしけん",
+			},
+		},
+		{
+			name: "Empty",
+		},
+	}
+
+	img := createPatternImage(8, 8)
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, img))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := embedChunks(encoded.Bytes(), tt.metadata.chunks())
+
+			// The chunks keep the PNG valid
+			decoded, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, img.Bounds(), decoded.Bounds())
+
+			m, err := ReadMetadata(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.True(t, tt.metadata.CaptureTime.Equal(m.CaptureTime))
+			m.CaptureTime = tt.metadata.CaptureTime
+			assert.Equal(t, tt.metadata, *m)
+		})
+	}
+}
+
+func TestReadMetadata_Encodings(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte("圧縮されたメモ"))
+	zw.Close()
+
+	var latin bytes.Buffer
+	zw = zlib.NewWriter(&latin)
+	zw.Write([]byte("Caf\xe9"))
+	zw.Close()
+
+	var chunks bytes.Buffer
+	// Compressed iTXt with a language tag and translated keyword
+	writePNGChunk(&chunks, "iTXt", append([]byte("Comment\x00\x01\x00ja\x00コメント\x00"), compressed.Bytes()...))
+	// zTXt and tEXt are Latin-1
+	writePNGChunk(&chunks, "zTXt", append([]byte("VRChat World Name\x00\x00"), latin.Bytes()...))
+	writePNGChunk(&chunks, "tEXt", []byte("Author\x00Jos\xe9"))
+	writePNGChunk(&chunks, "tEXt", []byte("Creation Time\x00Fri, 15 Mar 2024 21:30:05 +0900"))
+	// Broken chunks are skipped
+	writePNGChunk(&chunks, "iTXt", []byte("Software\x00\x01\x00\x00\x00not zlib"))
+
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, createPatternImage(4, 4)))
+
+	m, err := ReadMetadata(bytes.NewReader(embedChunks(encoded.Bytes(), chunks.Bytes())))
+	require.NoError(t, err)
+	assert.Equal(t, "圧縮されたメモ", m.Note)
+	assert.Equal(t, "Café", m.WorldName)
+	assert.Equal(t, "José", m.Author)
+	assert.Empty(t, m.Software)
+	assert.Equal(t, time.Date(2024, 3, 15, 12, 30, 5, 0, time.UTC), m.CaptureTime.UTC())
+
+	_, err = ReadMetadata(bytes.NewReader([]byte("GIF89a")))
+	assert.EqualError(t, err, "not a PNG file")
+}
+
+func TestPrepareImage_Metadata(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "photo.png")
+	require.NoError(t, createTestImage(imagePath, "png", 400, 300))
+	modTime := time.Date(2024, 3, 15, 21, 30, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(imagePath, modTime, modTime))
+
+	base := Options{
+		ImagePath:  imagePath,
+		ResizeMode: ResizeOriginal,
+		Note:       "メモ",
+		WorldID:    "wrld_test",
+		WorldName:  "Test World",
+	}
+
+	tests := []struct {
+		name        string
+		captureTime time.Time
+		metadata    MetadataOptions
+		expected    Metadata
+	}{
+		{
+			name:     "Everything",
+			metadata: MetadataOptions{Author: "yoshiken", Software: "vrc-print-upload/1.0.0"},
+			expected: Metadata{
+				Note:        "メモ",
+				WorldID:     "wrld_test",
+				WorldName:   "Test World",
+				CaptureTime: modTime,
+				SourceFile:  "photo.png",
+				Author:      "yoshiken",
+				Software:    "vrc-print-upload/1.0.0",
+			},
+		},
+		{
+			name: "Omitted fields",
+			metadata: MetadataOptions{
+				Author:   "yoshiken",
+				Software: "vrc-print-upload/1.0.0",
+				Omit:     []MetadataField{MetadataWorld, MetadataSourceFile, MetadataAuthor},
+			},
+			expected: Metadata{Note: "メモ", CaptureTime: modTime, Software: "vrc-print-upload/1.0.0"},
+		},
+		{
+			name:        "Capture time",
+			captureTime: time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC),
+			expected: Metadata{
+				Note:        "メモ",
+				WorldID:     "wrld_test",
+				WorldName:   "Test World",
+				CaptureTime: time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC),
+				SourceFile:  "photo.png",
+			},
+		},
+		{
+			name:     "Disabled",
+			metadata: MetadataOptions{Disabled: true, Author: "yoshiken"},
+		},
+	}
+
+	uploader := &Uploader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := base
+			opts.CaptureTime = tt.captureTime
+			opts.Metadata = tt.metadata
+			data, result, err := uploader.prepareImage(context.Background(), opts)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), result.Size)
+
+			m, err := ReadMetadata(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.True(t, tt.expected.CaptureTime.Equal(m.CaptureTime))
+			m.CaptureTime = tt.expected.CaptureTime
+			assert.Equal(t, tt.expected, *m)
+
+			_, err = png.Decode(bytes.NewReader(data))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseMetadataFields(t *testing.T) {
+	fields, err := ParseMetadataFields([]string{"author", "sourceFile"})
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataField{MetadataAuthor, MetadataSourceFile}, fields)
+
+	_, err = ParseMetadataFields([]string{"gps"})
+	assert.EqualError(t, err, "unknown metadata field: gps")
+}
+
+func TestEmbedChunks_Position(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 2, 2))))
+
+	var chunk bytes.Buffer
+	writePNGChunk(&chunk, "tEXt", []byte("Software\x00test"))
+	data := embedChunks(encoded.Bytes(), chunk.Bytes())
+
+	// The text chunk directly follows IHDR
+	assert.Equal(t, "IHDR", string(data[12:16]))
+	assert.Equal(t, "tEXt", string(data[pngHeaderSize+4:pngHeaderSize+8]))
+}
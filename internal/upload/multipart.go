@@ -0,0 +1,149 @@
+package upload
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"time"
+)
+
+// formField is a text field of the upload form
+type formField struct {
+	name  string
+	value string
+}
+
+// multipartBody is the upload form. It is a client.StreamBody: the form is
+// written through an io.Pipe while the request is sent instead of being
+// assembled in memory. Only the encoded PNG is held, since the optimizer
+// needs its size and retries resend it. Clients not built by client.New
+// read it like any io.Reader, into memory.
+type multipartBody struct {
+	imageData []byte
+	filename  string
+	fields    []formField
+	// timestamp is the time sent in the timestamp field
+	timestamp time.Time
+	boundary  string
+	// size is the length of the encoded form in bytes
+	size int64
+	// progress is told about every copy of the form that is sent
+	progress *progressReporter
+	// reader is the copy read through Read
+	reader io.ReadCloser
+}
+
+// newMultipartBody prepares the upload form for imageData and the metadata
+// in opts
+func newMultipartBody(imageData []byte, opts Options) (*multipartBody, error) {
+	var random [16]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate boundary: %w", err)
+	}
+
+	timestamp := opts.timestamp()
+	body := &multipartBody{
+		imageData: imageData,
+		filename:  filepath.Base(opts.ImagePath),
+		fields:    []formField{{name: "timestamp", value: timestamp.Format(time.RFC3339)}},
+		timestamp: timestamp,
+		boundary:  fmt.Sprintf("%x", random),
+		progress:  newProgressReporter(opts.Progress),
+	}
+
+	// Add optional fields
+	if opts.Note != "" {
+		body.fields = append(body.fields, formField{name: "note", value: opts.Note})
+	}
+	if opts.WorldID != "" {
+		body.fields = append(body.fields, formField{name: "worldId", value: opts.WorldID})
+	}
+	if opts.WorldName != "" {
+		body.fields = append(body.fields, formField{name: "worldName", value: opts.WorldName})
+	}
+
+	// Measure the form by writing it without keeping anything
+	counter := &countingWriter{}
+	if err := body.writeTo(counter); err != nil {
+		return nil, err
+	}
+	body.size = counter.n
+
+	return body, nil
+}
+
+// ContentType returns the Content-Type header for the form
+func (b *multipartBody) ContentType() string {
+	writer := multipart.NewWriter(io.Discard)
+	writer.SetBoundary(b.boundary)
+	return writer.FormDataContentType()
+}
+
+// Size returns the length of the encoded form in bytes
+func (b *multipartBody) Size() int64 {
+	return b.size
+}
+
+// writeTo writes the encoded form to w
+func (b *multipartBody) writeTo(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(b.boundary); err != nil {
+		return fmt.Errorf("failed to set boundary: %w", err)
+	}
+
+	// Add image file with explicit content type
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="image"; filename="%s"`, b.filename))
+	h.Set("Content-Type", "image/png")
+
+	part, err := writer.CreatePart(h)
+	if err != nil {
+		return fmt.Errorf("failed to create form part: %w", err)
+	}
+	if _, err := part.Write(b.imageData); err != nil {
+		return fmt.Errorf("failed to write image data: %w", err)
+	}
+
+	for _, field := range b.fields {
+		if err := writer.WriteField(field.name, field.value); err != nil {
+			return fmt.Errorf("failed to write %s: %w", field.name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+	return nil
+}
+
+// Open starts writing a fresh copy of the form into a pipe and reports it
+// as the upload phase. Closing the returned reader stops the writer.
+func (b *multipartBody) Open() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(b.writeTo(pw))
+	}()
+	b.progress.begin(PhaseUpload, b.size)
+	return &progressBody{ReadCloser: pr, reporter: b.progress}
+}
+
+// Read reads a copy of the form that is opened on first use
+func (b *multipartBody) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		b.reader = b.Open()
+	}
+	return b.reader.Read(p)
+}
+
+// countingWriter counts the bytes written to it and discards them
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
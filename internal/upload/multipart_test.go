@@ -0,0 +1,181 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"io"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoshiken/vrc-print-upload/internal/client"
+)
+
+func TestMultipartBody(t *testing.T) {
+	imageData := []byte("\x89PNG fake image data")
+	body, err := newMultipartBody(imageData, Options{
+		ImagePath: "/path/to/VRChat_shot.png",
+		Note:      "Test note",
+		WorldName: "Test World",
+		Timestamp: time.Date(2024, 3, 15, 21, 30, 5, 0, time.UTC),
+		TimeZone:  time.FixedZone("JST", 9*60*60),
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, body.Open())
+	require.NoError(t, err)
+	assert.Equal(t, body.size, n)
+
+	_, params, err := mime.ParseMediaType(body.ContentType())
+	require.NoError(t, err)
+	form, err := multipart.NewReader(&buf, params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+
+	require.Len(t, form.File["image"], 1)
+	assert.Equal(t, "VRChat_shot.png", form.File["image"][0].Filename)
+	assert.Equal(t, "image/png", form.File["image"][0].Header.Get("Content-Type"))
+	file, err := form.File["image"][0].Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, imageData, data)
+
+	assert.Equal(t, []string{"Test note"}, form.Value["note"])
+	assert.Equal(t, []string{"Test World"}, form.Value["worldName"])
+	assert.Equal(t, []string{"2024-03-16T06:30:05+09:00"}, form.Value["timestamp"])
+	assert.Nil(t, form.Value["worldId"])
+}
+
+// newStreamingClient returns an API client for the server at baseURL, which
+// streams StreamBody request bodies
+func newStreamingClient(baseURL string) *resty.Client {
+	return client.New(resty.New().SetBaseURL(baseURL)).Client
+}
+
+func TestSend_Streaming(t *testing.T) {
+	imageData := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(imageData)
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every attempt carries the whole form with a Content-Length
+		assert.Greater(t, r.ContentLength, int64(len(imageData)))
+		assert.Empty(t, r.TransferEncoding)
+
+		file, _, err := r.FormFile("image")
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(file)
+		if !assert.NoError(t, err) || !assert.Equal(t, imageData, data) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Fail the first attempt so that the form is sent again
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"fileId": "file_12345"}`))
+	}))
+	defer server.Close()
+
+	var uploads int
+	uploader := New(newStreamingClient(server.URL))
+	result, err := uploader.send(context.Background(), imageData, Options{
+		ImagePath: "test.png",
+		Progress: func(p Progress) {
+			if p.Phase == PhaseUpload && p.BytesSent == 0 {
+				uploads++
+			}
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "file_12345", result.FileID)
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Equal(t, 2, uploads)
+}
+
+func TestUpload_Streaming(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "test.png")
+	require.NoError(t, createTestImage(imagePath, "png", 400, 300))
+
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("image")
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received, err = io.ReadAll(file)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"fileId": "file_12345"}`))
+	}))
+	defer server.Close()
+
+	opts := Options{ImagePath: imagePath, ResizeMode: ResizeOriginal, Note: "Test note"}
+	result, err := New(newStreamingClient(server.URL)).Upload(context.Background(), opts)
+	require.NoError(t, err)
+
+	// The form carries the PNG the optimizer picked
+	assert.Equal(t, result.Encoding.Size, len(received))
+	meta, err := ReadMetadata(bytes.NewReader(received))
+	require.NoError(t, err)
+	assert.Equal(t, "Test note", meta.Note)
+	decoded, err := png.Decode(bytes.NewReader(received))
+	require.NoError(t, err)
+	assert.Equal(t, 400, decoded.Bounds().Dx())
+}
+
+// BenchmarkSend compares the memory for sending a large PNG with a client
+// that reads the form into memory and one that streams it
+func BenchmarkSend(b *testing.B) {
+	imageData := make([]byte, 16*1024*1024)
+	rand.New(rand.NewSource(1)).Read(imageData)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"fileId": "file_12345"}`))
+	}))
+	defer server.Close()
+
+	clients := []struct {
+		name   string
+		client *resty.Client
+	}{
+		{name: "Buffered", client: resty.New().SetBaseURL(server.URL)},
+		{name: "Streamed", client: newStreamingClient(server.URL)},
+	}
+	for _, c := range clients {
+		b.Run(c.name, func(b *testing.B) {
+			uploader := New(c.client)
+			opts := Options{ImagePath: "test.png"}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := uploader.send(context.Background(), imageData, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
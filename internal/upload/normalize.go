@@ -0,0 +1,162 @@
+package upload
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// DefaultBackground is used for flattening transparency when no background
+// color is given
+var DefaultBackground = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+// displayGamma is the gamma that sRGB displays approximately decode with
+const displayGamma = 2.2
+
+// normalizeImage converts img to 8-bit color and, unless opts.PreserveAlpha
+// is set, flattens transparency onto opts.Background
+func normalizeImage(img image.Image, opts Options) image.Image {
+	opaque := isOpaque(img)
+	if is8Bit(img) && (opaque || opts.PreserveAlpha) {
+		return img
+	}
+
+	// Clone converts 16-bit, CMYK and other color models to 8-bit NRGBA
+	dst := imaging.Clone(img)
+	if !opaque && !opts.PreserveAlpha {
+		background := opts.Background
+		if background == nil {
+			background = DefaultBackground
+		}
+		flatten(dst, color.NRGBAModel.Convert(background).(color.NRGBA))
+	}
+	return dst
+}
+
+// is8Bit reports whether the PNG encoder writes img with 8 bits per channel
+func is8Bit(img image.Image) bool {
+	switch img.(type) {
+	case *image.NRGBA, *image.RGBA, *image.Gray, *image.Paletted, *image.YCbCr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isOpaque reports whether img has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// flatten composites every pixel of img over an opaque background
+func flatten(img *image.NRGBA, background color.NRGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := uint32(img.Pix[i+3])
+		if a == 255 {
+			continue
+		}
+		for ch, bg := range []uint8{background.R, background.G, background.B} {
+			img.Pix[i+ch] = uint8((uint32(img.Pix[i+ch])*a + uint32(bg)*(255-a) + 127) / 255)
+		}
+		img.Pix[i+3] = 255
+	}
+}
+
+// pngColorInfo holds the color space chunks of a PNG
+type pngColorInfo struct {
+	// gamma is the file gamma from the gAMA chunk, or 0 if there is none
+	gamma float64
+	// srgb is set if the PNG has an sRGB chunk
+	srgb bool
+	// iccProfile is the name of the embedded ICC profile, if any
+	iccProfile string
+}
+
+// readPNGColorInfo reads the chunks before the image data of a PNG
+func readPNGColorInfo(r io.Reader) pngColorInfo {
+	var info pngColorInfo
+	br := bufio.NewReader(r)
+
+	signature := make([]byte, 8)
+	if _, err := io.ReadFull(br, signature); err != nil || string(signature) != "\x89PNG\r\n\x1a\n" {
+		return info
+	}
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return info
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+
+		switch chunkType {
+		case "IDAT", "IEND":
+			return info
+		case "gAMA":
+			var data [4]byte
+			if length != 4 {
+				return info
+			}
+			if _, err := io.ReadFull(br, data[:]); err != nil {
+				return info
+			}
+			info.gamma = float64(binary.BigEndian.Uint32(data[:])) / 100000
+			length = 0
+		case "sRGB":
+			info.srgb = true
+		case "iCCP":
+			// The profile name is a null-terminated Latin-1 string of at
+			// most 79 bytes
+			name, err := br.ReadString(0)
+			if err != nil || int64(len(name)) > length {
+				return info
+			}
+			info.iccProfile = strings.TrimSuffix(name, "\x00")
+			length -= int64(len(name))
+		}
+
+		// Skip the rest of the chunk and its CRC
+		if _, err := io.CopyN(io.Discard, br, length+4); err != nil {
+			return info
+		}
+	}
+}
+
+// applyPNGGamma re-encodes img for sRGB displays if its gAMA chunk declares
+// a different gamma. sRGB chunks and ICC profiles take precedence over gAMA;
+// since there is no pure-Go color management, non-sRGB ICC profiles are
+// left as they are.
+func applyPNGGamma(img image.Image, info pngColorInfo) image.Image {
+	if info.srgb || info.iccProfile != "" || info.gamma <= 0 {
+		return img
+	}
+
+	exponent := 1 / (info.gamma * displayGamma)
+	if math.Abs(exponent-1) < 0.01 {
+		return img
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(math.Round(math.Pow(float64(i)/255, exponent) * 255))
+	}
+
+	// Gamma applies to the color channels, not alpha
+	dst := imaging.Clone(img)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i] = lut[dst.Pix[i]]
+		dst.Pix[i+1] = lut[dst.Pix[i+1]]
+		dst.Pix[i+2] = lut[dst.Pix[i+2]]
+	}
+	return dst
+}
@@ -0,0 +1,173 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeImage(t *testing.T) {
+	halfRed := color.NRGBA{R: 255, A: 128}
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		opts     Options
+		expected color.NRGBA
+	}{
+		{
+			name:     "Flatten onto default background",
+			img:      paintImage(image.NewNRGBA(image.Rect(0, 0, 4, 4)), halfRed),
+			expected: color.NRGBA{R: 255, G: 127, B: 127, A: 255},
+		},
+		{
+			name:     "Flatten onto custom background",
+			img:      paintImage(image.NewNRGBA(image.Rect(0, 0, 4, 4)), halfRed),
+			opts:     Options{Background: color.NRGBA{B: 255, A: 255}},
+			expected: color.NRGBA{R: 128, B: 127, A: 255},
+		},
+		{
+			name:     "Preserve alpha",
+			img:      paintImage(image.NewNRGBA(image.Rect(0, 0, 4, 4)), halfRed),
+			opts:     Options{PreserveAlpha: true},
+			expected: halfRed,
+		},
+		{
+			name:     "16-bit to 8-bit",
+			img:      paintImage(image.NewNRGBA64(image.Rect(0, 0, 4, 4)), color.NRGBA64{R: 0xffff, G: 0x8080, A: 0xffff}),
+			expected: color.NRGBA{R: 255, G: 128, A: 255},
+		},
+		{
+			name:     "16-bit with alpha",
+			img:      paintImage(image.NewNRGBA64(image.Rect(0, 0, 4, 4)), color.NRGBA64{R: 0xffff, A: 0x8080}),
+			expected: color.NRGBA{R: 255, G: 127, B: 127, A: 255},
+		},
+		{
+			name:     "CMYK",
+			img:      paintImage(image.NewCMYK(image.Rect(0, 0, 4, 4)), color.CMYK{C: 255}),
+			expected: color.NRGBA{G: 255, B: 255, A: 255},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := normalizeImage(tt.img, tt.opts)
+			assert.True(t, is8Bit(result), "got %T", result)
+
+			actual := color.NRGBAModel.Convert(result.At(1, 1)).(color.NRGBA)
+			assert.InDelta(t, tt.expected.R, actual.R, 1, "%v", actual)
+			assert.InDelta(t, tt.expected.G, actual.G, 1, "%v", actual)
+			assert.InDelta(t, tt.expected.B, actual.B, 1, "%v", actual)
+			assert.Equal(t, tt.expected.A, actual.A)
+		})
+	}
+}
+
+func TestNormalizeImage_OpaqueUnchanged(t *testing.T) {
+	img := paintImage(image.NewNRGBA(image.Rect(0, 0, 4, 4)), color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	assert.Same(t, img, normalizeImage(img, Options{}))
+}
+
+func TestLoadImage_PNGGamma(t *testing.T) {
+	gray := color.NRGBA{R: 128, G: 128, B: 128, A: 255}
+
+	tests := []struct {
+		name     string
+		chunks   map[string][]byte
+		expected uint8
+	}{
+		{name: "No color chunks", expected: 128},
+		{name: "sRGB gamma", chunks: map[string][]byte{"gAMA": gammaChunk(45455)}, expected: 128},
+		{name: "Linear gamma", chunks: map[string][]byte{"gAMA": gammaChunk(100000)}, expected: 186},
+		{name: "sRGB chunk wins", chunks: map[string][]byte{"sRGB": {0}, "gAMA": gammaChunk(100000)}, expected: 128},
+		{name: "ICC profile wins", chunks: map[string][]byte{"iCCP": []byte("Display P3\x00\x00x"), "gAMA": gammaChunk(100000)}, expected: 128},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "gamma.png")
+			require.NoError(t, writePNGWithChunks(path, paintImage(image.NewNRGBA(image.Rect(0, 0, 8, 8)), gray), tt.chunks))
+
+			img, err := loadImage(context.Background(), path, Limits{}, nil)
+			require.NoError(t, err)
+
+			actual := color.NRGBAModel.Convert(img.At(4, 4)).(color.NRGBA)
+			assert.InDelta(t, tt.expected, actual.R, 1)
+			assert.Equal(t, uint8(255), actual.A)
+		})
+	}
+}
+
+func TestReadPNGColorInfo(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, paintImage(image.NewNRGBA(image.Rect(0, 0, 2, 2)), color.NRGBA{A: 255})))
+	data := insertPNGChunks(buf.Bytes(), map[string][]byte{
+		"gAMA": gammaChunk(50000),
+		"iCCP": []byte("sRGB IEC61966-2.1\x00\x00profile"),
+	})
+
+	info := readPNGColorInfo(bytes.NewReader(data))
+	assert.InDelta(t, 0.5, info.gamma, 1e-9)
+	assert.Equal(t, "sRGB IEC61966-2.1", info.iccProfile)
+	assert.False(t, info.srgb)
+
+	assert.Equal(t, pngColorInfo{}, readPNGColorInfo(bytes.NewReader([]byte("not a png"))))
+}
+
+// paintImage fills img with c
+func paintImage(img draw.Image, c color.Color) draw.Image {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// gammaChunk returns the payload of a gAMA chunk for gamma * 100000
+func gammaChunk(gamma uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, gamma)
+}
+
+// writePNGWithChunks encodes img as a PNG with extra chunks after IHDR
+func writePNGWithChunks(path string, img image.Image, chunks map[string][]byte) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(path, insertPNGChunks(buf.Bytes(), chunks), 0644)
+}
+
+// insertPNGChunks inserts chunks after the IHDR chunk of an encoded PNG.
+// Known chunks are written in a fixed order.
+func insertPNGChunks(data []byte, chunks map[string][]byte) []byte {
+	// Signature plus IHDR: length, type, 13 bytes of data and CRC
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	for _, name := range []string{"sRGB", "iCCP", "gAMA"} {
+		payload, ok := chunks[name]
+		if !ok {
+			continue
+		}
+		binary.Write(&out, binary.BigEndian, uint32(len(payload)))
+		out.WriteString(name)
+		out.Write(payload)
+		binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(name), payload...)))
+	}
+	out.Write(data[ihdrEnd:])
+	return out.Bytes()
+}
@@ -0,0 +1,231 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// Strategy names how the optimizer made the PNG fit
+type Strategy string
+
+const (
+	// StrategyDefault is the standard PNG encoding
+	StrategyDefault Strategy = "default"
+	// StrategyBestCompression uses the highest PNG compression level
+	StrategyBestCompression Strategy = "best-compression"
+	// StrategyQuantize reduces the image to a dithered 256-color palette
+	StrategyQuantize Strategy = "quantize"
+	// StrategyDownscale reduces the resolution step by step
+	StrategyDownscale Strategy = "downscale"
+)
+
+const (
+	// downscaleStep is the factor each downscale step applies to the
+	// resolution
+	downscaleStep = 0.85
+	// minDownscaleSize is the smallest longer side the optimizer
+	// downscales to before giving up
+	minDownscaleSize = 256
+	// quantizeSamples caps the pixels that are sampled to build a palette
+	quantizeSamples = 1 << 16
+)
+
+// OutputOptions controls how the processed image is encoded
+type OutputOptions struct {
+	// MaxBytes is the largest acceptable PNG; zero means MaxImageSize
+	MaxBytes int
+	// Quantize allows reducing the image to a 256-color palette with
+	// Floyd-Steinberg dithering
+	Quantize bool
+	// Shrink always uses the smallest encoding instead of only when the
+	// standard encoding is too large, so that uploads go out faster
+	Shrink bool
+}
+
+// EncodeResult reports how the uploaded PNG was produced
+type EncodeResult struct {
+	Strategy Strategy
+	Width    int
+	Height   int
+	// Size is the size of the PNG in bytes
+	Size int
+}
+
+// encodeOutput encodes img as a PNG with the text chunks of meta, if any,
+// that fits the size limit in out. It tries, in order, the standard
+// encoding, the best compression level, palette quantization if allowed,
+// and stepwise downscaling.
+func encodeOutput(ctx context.Context, img image.Image, out OutputOptions, meta *Metadata, progress *progressReporter) ([]byte, *EncodeResult, error) {
+	maxBytes := out.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = MaxImageSize
+	}
+	chunks := meta.chunks()
+
+	encode := func(img image.Image, level png.CompressionLevel, strategy Strategy) ([]byte, *EncodeResult, error) {
+		var buf bytes.Buffer
+		progress.begin(PhaseEncode, 0)
+		encoder := &png.Encoder{CompressionLevel: level}
+		if err := encoder.Encode(&contextWriter{ctx: ctx, w: &progressWriter{w: &buf, reporter: progress}}, img); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode image as PNG: %w", err)
+		}
+		progress.finish()
+
+		data := embedChunks(buf.Bytes(), chunks)
+		return data, &EncodeResult{
+			Strategy: strategy,
+			Width:    img.Bounds().Dx(),
+			Height:   img.Bounds().Dy(),
+			Size:     len(data),
+		}, nil
+	}
+
+	if !out.Shrink {
+		data, result, err := encode(img, png.DefaultCompression, StrategyDefault)
+		if err != nil || len(data) <= maxBytes {
+			return data, result, err
+		}
+	}
+
+	data, result, err := encode(img, png.BestCompression, StrategyBestCompression)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) <= maxBytes && !(out.Shrink && out.Quantize) {
+		return data, result, nil
+	}
+
+	if out.Quantize {
+		quantized, quantizedResult, err := encode(quantizeImage(img), png.BestCompression, StrategyQuantize)
+		if err != nil {
+			return nil, nil, err
+		}
+		// When shrinking, a palette only pays off if it is smaller
+		if len(quantized) < len(data) {
+			data, result = quantized, quantizedResult
+		}
+		if len(data) <= maxBytes {
+			return data, result, nil
+		}
+	}
+
+	// Downscale from the full-size image each time to avoid compounding blur
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	for scale := downscaleStep; ; scale *= downscaleStep {
+		w := int(math.Round(float64(bounds.Dx()) * scale))
+		h := int(math.Round(float64(bounds.Dy()) * scale))
+		if max(w, h) < minDownscaleSize {
+			break
+		}
+		width, height = w, h
+
+		var scaled image.Image = imaging.Resize(img, w, h, imaging.Lanczos)
+		if out.Quantize {
+			scaled = quantizeImage(scaled)
+		}
+
+		data, result, err := encode(scaled, png.BestCompression, StrategyDownscale)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(data) <= maxBytes {
+			return data, result, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("encoded image too large: still over %d bytes after downscaling to %dx%d", maxBytes, width, height)
+}
+
+// quantizeImage reduces img to a 256-color median-cut palette with
+// Floyd-Steinberg dithering
+func quantizeImage(img image.Image) *image.Paletted {
+	src := imaging.Clone(img)
+	dst := image.NewPaletted(src.Bounds(), medianCutPalette(src, 256))
+	draw.FloydSteinberg.Draw(dst, dst.Bounds(), src, src.Bounds().Min)
+	return dst
+}
+
+// medianCutPalette builds a palette of up to n colors by repeatedly
+// splitting the box of sampled colors with the widest channel range at its
+// median
+func medianCutPalette(img *image.NRGBA, n int) color.Palette {
+	pixels := len(img.Pix) / 4
+	stride := max(1, pixels/quantizeSamples)
+
+	samples := make([][4]uint8, 0, pixels/stride+1)
+	for i := 0; i < pixels; i += stride {
+		p := img.Pix[i*4 : i*4+4]
+		samples = append(samples, [4]uint8{p[0], p[1], p[2], p[3]})
+	}
+
+	boxes := [][][4]uint8{samples}
+	for len(boxes) < n {
+		// Split the box with the widest range in any channel
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, r := widestChannel(box)
+			if r > bestRange {
+				best, bestChannel, bestRange = i, channel, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestChannel] < box[j][bestChannel] })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var sum [4]int
+		for _, c := range box {
+			for ch := range sum {
+				sum[ch] += int(c[ch])
+			}
+		}
+		palette = append(palette, color.NRGBA{
+			R: uint8(sum[0] / len(box)),
+			G: uint8(sum[1] / len(box)),
+			B: uint8(sum[2] / len(box)),
+			A: uint8(sum[3] / len(box)),
+		})
+	}
+	return palette
+}
+
+// widestChannel returns the channel with the largest value range in box
+func widestChannel(box [][4]uint8) (int, int) {
+	lo := [4]uint8{255, 255, 255, 255}
+	var hi [4]uint8
+	for _, c := range box {
+		for ch := range c {
+			lo[ch] = min(lo[ch], c[ch])
+			hi[ch] = max(hi[ch], c[ch])
+		}
+	}
+
+	channel, r := 0, 0
+	for ch := range lo {
+		if d := int(hi[ch]) - int(lo[ch]); d > r {
+			channel, r = ch, d
+		}
+	}
+	return channel, r
+}
@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeOutput(t *testing.T) {
+	// Sizes of the photo-like test image with each strategy, to pick limits
+	// that force the optimizer to a particular step
+	img := createNoisyGradient(320, 180)
+	defaultSize := encodedSize(t, img, png.DefaultCompression)
+	bestSize := encodedSize(t, img, png.BestCompression)
+	quantizedSize := encodedSize(t, quantizeImage(img), png.BestCompression)
+	require.Less(t, bestSize, defaultSize)
+	require.Less(t, quantizedSize, bestSize)
+
+	// Sizes after the first downscale step, so the downscale cases stop there
+	scaled := imaging.Resize(img, 272, 153, imaging.Lanczos)
+	scaledSize := encodedSize(t, scaled, png.BestCompression)
+	scaledQuantizedSize := encodedSize(t, quantizeImage(scaled), png.BestCompression)
+	require.Less(t, scaledSize, bestSize)
+	require.Less(t, scaledQuantizedSize, quantizedSize)
+
+	tests := []struct {
+		name             string
+		out              OutputOptions
+		expectedStrategy Strategy
+		expectError      bool
+		downscaled       bool
+	}{
+		{name: "Fits as is", out: OutputOptions{}, expectedStrategy: StrategyDefault},
+		{name: "Best compression", out: OutputOptions{MaxBytes: bestSize}, expectedStrategy: StrategyBestCompression},
+		{name: "Quantize", out: OutputOptions{MaxBytes: quantizedSize, Quantize: true}, expectedStrategy: StrategyQuantize},
+		{name: "Downscale without quantizing", out: OutputOptions{MaxBytes: scaledSize}, expectedStrategy: StrategyDownscale, downscaled: true},
+		{name: "Downscale after quantizing", out: OutputOptions{MaxBytes: scaledQuantizedSize, Quantize: true}, expectedStrategy: StrategyDownscale, downscaled: true},
+		{name: "Shrink", out: OutputOptions{Shrink: true}, expectedStrategy: StrategyBestCompression},
+		{name: "Shrink with quantization", out: OutputOptions{Shrink: true, Quantize: true}, expectedStrategy: StrategyQuantize},
+		{name: "Impossible limit", out: OutputOptions{MaxBytes: 100}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, result, err := encodeOutput(context.Background(), img, tt.out, nil, nil)
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "encoded image too large")
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStrategy, result.Strategy)
+			assert.Equal(t, len(data), result.Size)
+			if tt.out.MaxBytes > 0 {
+				assert.LessOrEqual(t, len(data), tt.out.MaxBytes)
+			}
+
+			decoded, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, result.Width, decoded.Bounds().Dx())
+			assert.Equal(t, result.Height, decoded.Bounds().Dy())
+			if tt.downscaled {
+				assert.Less(t, result.Width, 320)
+				// The aspect ratio is kept
+				assert.InDelta(t, 320.0/180.0, float64(result.Width)/float64(result.Height), 0.02)
+			} else {
+				assert.Equal(t, 320, result.Width)
+			}
+		})
+	}
+}
+
+func TestQuantizeImage(t *testing.T) {
+	img := createNoisyGradient(200, 100)
+	quantized := quantizeImage(img)
+
+	assert.LessOrEqual(t, len(quantized.Palette), 256)
+	assert.Equal(t, img.Bounds(), quantized.Bounds())
+
+	// Dithering keeps the average color close to the original
+	var origSum, quantSum float64
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			o := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			q := color.NRGBAModel.Convert(quantized.At(x, y)).(color.NRGBA)
+			origSum += float64(o.R) + float64(o.G) + float64(o.B)
+			quantSum += float64(q.R) + float64(q.G) + float64(q.B)
+		}
+	}
+	assert.InDelta(t, origSum/20000, quantSum/20000, 3)
+}
+
+// createNoisyGradient creates a deterministic gradient with noise, which
+// compresses about as badly as a photo
+func createNoisyGradient(width, height int) *image.NRGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			noise := rng.Intn(24)
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x*200/width + noise),
+				G: uint8(y*200/height + noise),
+				B: uint8(128 + noise),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func encodedSize(t *testing.T, img image.Image, level png.CompressionLevel) int {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, (&png.Encoder{CompressionLevel: level}).Encode(&buf, img))
+	return buf.Len()
+}
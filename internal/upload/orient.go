@@ -0,0 +1,168 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/disintegration/imaging"
+)
+
+// Rotation is a clockwise rotation in degrees
+type Rotation int
+
+const (
+	Rotate0   Rotation = 0
+	Rotate90  Rotation = 90
+	Rotate180 Rotation = 180
+	Rotate270 Rotation = 270
+)
+
+// Valid reports whether r is a multiple of 90 degrees in [0, 270]
+func (r Rotation) Valid() bool {
+	switch r {
+	case Rotate0, Rotate90, Rotate180, Rotate270:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateOrientation checks the manual orientation options
+func validateOrientation(opts Options) error {
+	if !opts.Rotate.Valid() {
+		return fmt.Errorf("invalid rotation: %d (must be 0, 90, 180 or 270)", opts.Rotate)
+	}
+	return nil
+}
+
+// orientImage applies the manual rotation in opts, followed by the flips.
+// EXIF orientation has already been applied when the image was decoded.
+func orientImage(img image.Image, opts Options) image.Image {
+	// imaging rotates counter-clockwise
+	switch opts.Rotate {
+	case Rotate90:
+		img = imaging.Rotate270(img)
+	case Rotate180:
+		img = imaging.Rotate180(img)
+	case Rotate270:
+		img = imaging.Rotate90(img)
+	}
+
+	if opts.FlipHorizontal {
+		img = imaging.FlipH(img)
+	}
+	if opts.FlipVertical {
+		img = imaging.FlipV(img)
+	}
+	return img
+}
+
+// applyEXIFOrientation turns an image stored with the given EXIF
+// orientation upright
+func applyEXIFOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+// readEXIFOrientation returns the EXIF orientation of a JPEG, or 1 if the
+// JPEG has none
+func readEXIFOrientation(r io.Reader) int {
+	br := bufio.NewReader(r)
+
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return 1
+	}
+
+	// EXIF lives in an APP1 segment before the image data
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(br, marker[:]); err != nil || marker[0] != 0xff {
+			return 1
+		}
+		if marker[1] == 0xda { // Start of scan
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return 1
+		}
+		if marker[1] != 0xe1 {
+			if _, err := br.Discard(length); err != nil {
+				return 1
+			}
+			continue
+		}
+
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(br, segment); err != nil {
+			return 1
+		}
+		if orientation := parseEXIFOrientation(segment); orientation != 0 {
+			return orientation
+		}
+	}
+}
+
+// parseEXIFOrientation reads the orientation tag from the first IFD of an
+// APP1 segment, returning 0 if there is none
+func parseEXIFOrientation(segment []byte) int {
+	const orientationTag = 0x0112
+
+	tiff, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		// Orientation is a single SHORT
+		if order.Uint16(tiff[entry:]) == orientationTag && order.Uint16(tiff[entry+2:]) == 3 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	quadrantRed   = color.NRGBA{R: 255, A: 255}
+	quadrantGreen = color.NRGBA{G: 255, A: 255}
+	quadrantBlue  = color.NRGBA{B: 255, A: 255}
+	quadrantWhite = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+func TestLoadImage_EXIFOrientation(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// stored turns the upright image into what a camera would store along
+	// with the orientation tag
+	tests := []struct {
+		orientation uint16
+		stored      func(image.Image) *image.NRGBA
+	}{
+		{orientation: 1, stored: imaging.Clone},
+		{orientation: 2, stored: imaging.FlipH},
+		{orientation: 3, stored: imaging.Rotate180},
+		{orientation: 4, stored: imaging.FlipV},
+		{orientation: 5, stored: imaging.Transpose},
+		{orientation: 6, stored: imaging.Rotate90},
+		{orientation: 7, stored: imaging.Transverse},
+		{orientation: 8, stored: imaging.Rotate270},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Orientation %d", tt.orientation), func(t *testing.T) {
+			path := filepath.Join(tempDir, "orientation.jpg")
+			require.NoError(t, writeJPEGWithOrientation(path, tt.stored(createQuadrantImage()), tt.orientation))
+
+			img, err := loadImage(context.Background(), path, Limits{}, nil)
+			require.NoError(t, err)
+			assertQuadrants(t, img, quadrantRed, quadrantGreen, quadrantBlue, quadrantWhite)
+		})
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	tests := []struct {
+		name                 string
+		opts                 Options
+		tl, tr, bl, br       color.NRGBA
+		expectedW, expectedH int
+	}{
+		{name: "None", opts: Options{}, tl: quadrantRed, tr: quadrantGreen, bl: quadrantBlue, br: quadrantWhite, expectedW: 64, expectedH: 32},
+		{name: "Rotate 90", opts: Options{Rotate: Rotate90}, tl: quadrantBlue, tr: quadrantRed, bl: quadrantWhite, br: quadrantGreen, expectedW: 32, expectedH: 64},
+		{name: "Rotate 180", opts: Options{Rotate: Rotate180}, tl: quadrantWhite, tr: quadrantBlue, bl: quadrantGreen, br: quadrantRed, expectedW: 64, expectedH: 32},
+		{name: "Rotate 270", opts: Options{Rotate: Rotate270}, tl: quadrantGreen, tr: quadrantWhite, bl: quadrantRed, br: quadrantBlue, expectedW: 32, expectedH: 64},
+		{name: "Flip horizontal", opts: Options{FlipHorizontal: true}, tl: quadrantGreen, tr: quadrantRed, bl: quadrantWhite, br: quadrantBlue, expectedW: 64, expectedH: 32},
+		{name: "Flip vertical", opts: Options{FlipVertical: true}, tl: quadrantBlue, tr: quadrantWhite, bl: quadrantRed, br: quadrantGreen, expectedW: 64, expectedH: 32},
+		{name: "Rotate 90 then flip horizontal", opts: Options{Rotate: Rotate90, FlipHorizontal: true}, tl: quadrantRed, tr: quadrantBlue, bl: quadrantGreen, br: quadrantWhite, expectedW: 32, expectedH: 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := orientImage(createQuadrantImage(), tt.opts)
+			assert.Equal(t, tt.expectedW, img.Bounds().Dx())
+			assert.Equal(t, tt.expectedH, img.Bounds().Dy())
+			assertQuadrants(t, img, tt.tl, tt.tr, tt.bl, tt.br)
+		})
+	}
+}
+
+func TestPrepareImage_InvalidRotation(t *testing.T) {
+	uploader := &Uploader{}
+	_, _, err := uploader.prepareImage(context.Background(), Options{ImagePath: "unused.png", Rotate: 45})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid rotation")
+}
+
+// createQuadrantImage creates a 64x32 image with red, green, blue and white
+// quadrants from the top-left in reading order
+func createQuadrantImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			c := []color.NRGBA{quadrantRed, quadrantGreen, quadrantBlue, quadrantWhite}[(y/16)*2+x/32]
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// assertQuadrants checks the color at the center of each quadrant, allowing
+// for JPEG compression artifacts
+func assertQuadrants(t *testing.T, img image.Image, tl, tr, bl, br color.NRGBA) {
+	t.Helper()
+
+	b := img.Bounds()
+	points := []image.Point{
+		{b.Min.X + b.Dx()/4, b.Min.Y + b.Dy()/4},
+		{b.Min.X + b.Dx()*3/4, b.Min.Y + b.Dy()/4},
+		{b.Min.X + b.Dx()/4, b.Min.Y + b.Dy()*3/4},
+		{b.Min.X + b.Dx()*3/4, b.Min.Y + b.Dy()*3/4},
+	}
+	for i, expected := range []color.NRGBA{tl, tr, bl, br} {
+		actual := color.NRGBAModel.Convert(img.At(points[i].X, points[i].Y)).(color.NRGBA)
+		assert.InDelta(t, expected.R, actual.R, 24, "quadrant %d: %v", i, actual)
+		assert.InDelta(t, expected.G, actual.G, 24, "quadrant %d: %v", i, actual)
+		assert.InDelta(t, expected.B, actual.B, 24, "quadrant %d: %v", i, actual)
+	}
+}
+
+// writeJPEGWithOrientation encodes img as a JPEG with an EXIF APP1 segment
+// holding only the orientation tag
+func writeJPEGWithOrientation(path string, img image.Image, orientation uint16) error {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 95}); err != nil {
+		return err
+	}
+
+	// Big-endian TIFF header followed by an IFD with a single SHORT entry
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.BigEndian, uint16(3))
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, orientation)
+	binary.Write(&tiff, binary.BigEndian, uint16(0))
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(encoded.Bytes()[:2]) // SOI
+	out.Write([]byte{0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(encoded.Bytes()[2:])
+
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
@@ -0,0 +1,65 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+)
+
+// ImagePreview shows an image as it would be uploaded
+type ImagePreview struct {
+	// Width, Height and Size describe the PNG that would be uploaded
+	Width    int
+	Height   int
+	Size     int
+	Strategy Strategy
+	// Thumbnail is the processed image scaled to fit maxSize: a JPEG, or a
+	// PNG if transparency is preserved. ThumbnailType is its MIME type.
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// PreviewImage runs the whole upload pipeline on the image at opts.ImagePath,
+// including the PNG encoding, without sending anything, and returns a
+// thumbnail of the result along with its final dimensions and size
+func PreviewImage(ctx context.Context, opts Options, maxSize int) (*ImagePreview, error) {
+	opts.CaptureTime = captureTime(opts)
+
+	data, encoding, err := (&Uploader{}).prepareImage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the encoded PNG rather than reuse the processed image, since
+	// the optimizer may have quantized or downscaled it
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode processed image: %w", err)
+	}
+	thumb := imaging.Fit(img, maxSize, maxSize, imaging.Linear)
+
+	preview := &ImagePreview{
+		Width:    encoding.Width,
+		Height:   encoding.Height,
+		Size:     encoding.Size,
+		Strategy: encoding.Strategy,
+	}
+
+	var buf bytes.Buffer
+	if opts.PreserveAlpha {
+		err = png.Encode(&buf, thumb)
+		preview.ThumbnailType = "image/png"
+	} else {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		preview.ThumbnailType = "image/jpeg"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	preview.Thumbnail = buf.Bytes()
+	return preview, nil
+}
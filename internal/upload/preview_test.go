@@ -0,0 +1,83 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewImage(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "photo.png")
+	require.NoError(t, createTestImage(imagePath, "png", 400, 300))
+
+	tests := []struct {
+		name           string
+		opts           Options
+		expectedWidth  int
+		expectedHeight int
+		expectedThumbW int
+		expectedThumbH int
+		expectedType   string
+	}{
+		{
+			name:           "Stretch",
+			opts:           Options{ImagePath: imagePath},
+			expectedWidth:  1920,
+			expectedHeight: 1080,
+			expectedThumbW: 320,
+			expectedThumbH: 180,
+			expectedType:   "image/jpeg",
+		},
+		{
+			name:           "Rotated original",
+			opts:           Options{ImagePath: imagePath, ResizeMode: ResizeOriginal, Rotate: Rotate90},
+			expectedWidth:  300,
+			expectedHeight: 400,
+			expectedThumbW: 240,
+			expectedThumbH: 320,
+			expectedType:   "image/jpeg",
+		},
+		{
+			name:           "Transparency",
+			opts:           Options{ImagePath: imagePath, ResizeMode: ResizeOriginal, PreserveAlpha: true},
+			expectedWidth:  400,
+			expectedHeight: 300,
+			expectedThumbW: 320,
+			expectedThumbH: 240,
+			expectedType:   "image/png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := PreviewImage(context.Background(), tt.opts, 320)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedWidth, preview.Width)
+			assert.Equal(t, tt.expectedHeight, preview.Height)
+			assert.Equal(t, StrategyDefault, preview.Strategy)
+			assert.Equal(t, tt.expectedType, preview.ThumbnailType)
+
+			// The size is that of the PNG an upload would send
+			data, _, err := (&Uploader{}).prepareImage(context.Background(), tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), preview.Size)
+
+			thumb, format, err := image.Decode(bytes.NewReader(preview.Thumbnail))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedType, "image/"+format)
+			assert.Equal(t, tt.expectedThumbW, thumb.Bounds().Dx())
+			assert.Equal(t, tt.expectedThumbH, thumb.Bounds().Dy())
+		})
+	}
+}
+
+func TestPreviewImage_Invalid(t *testing.T) {
+	_, err := PreviewImage(context.Background(), Options{ImagePath: filepath.Join(t.TempDir(), "missing.png")}, 320)
+	assert.Error(t, err)
+}
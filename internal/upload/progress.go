@@ -0,0 +1,167 @@
+package upload
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Phase identifies the step of an upload that progress is reported for
+type Phase string
+
+const (
+	PhaseDecode Phase = "decode"
+	PhaseResize Phase = "resize"
+	PhaseEncode Phase = "encode"
+	PhaseUpload Phase = "upload"
+)
+
+// progressInterval limits how often progress callbacks fire within a phase
+const progressInterval = 100 * time.Millisecond
+
+// Progress describes how far an upload has come
+type Progress struct {
+	Phase Phase
+	// Bytes processed so far in the current phase
+	BytesSent int64
+	// Total bytes of the current phase, or 0 if unknown
+	Total int64
+	// Rate in bytes per second since the phase started
+	Rate float64
+}
+
+// ProgressFunc receives progress updates. It is called from the goroutine
+// doing the work and must not block for long.
+type ProgressFunc func(Progress)
+
+// progressReporter turns byte counts into throttled Progress callbacks.
+// A nil reporter or one without a callback does nothing.
+type progressReporter struct {
+	fn ProgressFunc
+
+	mu    sync.Mutex
+	phase Phase
+	done  int64
+	total int64
+	start time.Time
+	last  time.Time
+}
+
+func newProgressReporter(fn ProgressFunc) *progressReporter {
+	if fn == nil {
+		return nil
+	}
+	return &progressReporter{fn: fn}
+}
+
+// begin starts a new phase and reports it immediately
+func (p *progressReporter) begin(phase Phase, total int64) {
+	if p == nil {
+		return
+	}
+
+	if total < 0 {
+		total = 0
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	p.phase = phase
+	p.done = 0
+	p.total = total
+	p.start = now
+	p.last = now
+	progress := p.snapshot(now)
+	p.mu.Unlock()
+
+	p.fn(progress)
+}
+
+// add records n processed bytes and reports them if enough time has passed
+func (p *progressReporter) add(n int) {
+	if p == nil || n <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	p.done += int64(n)
+	if now.Sub(p.last) < progressInterval && (p.total == 0 || p.done < p.total) {
+		p.mu.Unlock()
+		return
+	}
+	p.last = now
+	progress := p.snapshot(now)
+	p.mu.Unlock()
+
+	p.fn(progress)
+}
+
+// finish reports the final state of the current phase
+func (p *progressReporter) finish() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	if p.total < p.done {
+		p.total = p.done
+	}
+	p.last = now
+	progress := p.snapshot(now)
+	p.mu.Unlock()
+
+	p.fn(progress)
+}
+
+func (p *progressReporter) snapshot(now time.Time) Progress {
+	progress := Progress{
+		Phase:     p.phase,
+		BytesSent: p.done,
+		Total:     p.total,
+	}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		progress.Rate = float64(p.done) / elapsed
+	}
+	return progress
+}
+
+// progressReader reports bytes read through it
+type progressReader struct {
+	r        io.Reader
+	reporter *progressReporter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.reporter.add(n)
+	return n, err
+}
+
+// progressWriter reports bytes written through it
+type progressWriter struct {
+	w        io.Writer
+	reporter *progressReporter
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.reporter.add(n)
+	return n, err
+}
+
+// progressBody wraps a request body and reports bytes as they are sent
+type progressBody struct {
+	io.ReadCloser
+	reporter *progressReporter
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.reporter.add(n)
+	if err == io.EOF {
+		b.reporter.finish()
+	}
+	return n, err
+}
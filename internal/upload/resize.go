@@ -0,0 +1,175 @@
+package upload
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// ResizeMode selects how an image is fitted to the print resolution
+type ResizeMode string
+
+const (
+	// ResizeStretch scales to exactly 1920x1080 (or 1080x1920), distorting
+	// images with a different aspect ratio. This is the legacy behavior and
+	// the default when no mode is set.
+	ResizeStretch ResizeMode = "stretch"
+	// ResizeFit scales the whole image into the print and letterboxes the
+	// remaining area with the pad color
+	ResizeFit ResizeMode = "fit"
+	// ResizeFill scales the image to cover the print and crops the overflow
+	// around the crop anchor
+	ResizeFill ResizeMode = "fill"
+	// ResizeOriginal keeps the original resolution, limited to MaxResolution
+	ResizeOriginal ResizeMode = "original"
+)
+
+// DefaultPadColor is used for letterboxing when no pad color is given
+var DefaultPadColor = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+
+// ParseResizeMode parses a resize mode name. An empty name selects the
+// legacy stretch mode.
+func ParseResizeMode(name string) (ResizeMode, error) {
+	switch mode := ResizeMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "":
+		return ResizeStretch, nil
+	case ResizeStretch, ResizeFit, ResizeFill, ResizeOriginal:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown resize mode: %s", name)
+	}
+}
+
+// ParseHexColor parses colors in #RRGGBB or #RRGGBBAA notation
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+
+	if len(hex) == 6 {
+		return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// printSize returns the print resolution matching the image orientation:
+// 1920x1080 for landscape, 1080x1920 otherwise
+func printSize(bounds image.Rectangle) (int, int) {
+	if bounds.Dx() > bounds.Dy() {
+		return Print1080pWidth, Print1080pHeight
+	}
+	return Print1080pHeight, Print1080pWidth
+}
+
+// resizeImage resizes the image according to the resize mode, pad color
+// and crop anchor in opts. With a frame, the photo is fitted to the opening
+// of the frame instead and placed on a transparent print for the frame step
+// to draw around.
+func resizeImage(img image.Image, opts Options) image.Image {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	if opts.Frame != nil {
+		targetWidth, targetHeight := outputSize(width, height, opts.ResizeMode)
+		inner := opts.Frame.Border.inner(targetWidth, targetHeight)
+		if !inner.Empty() {
+			// The print keeps the size of the photo in ResizeOriginal mode,
+			// so only the opening's aspect ratio is left to fill
+			mode := opts.ResizeMode
+			if mode == ResizeOriginal {
+				mode = ResizeFill
+			}
+			photo := fitImage(img, inner.Dx(), inner.Dy(), mode, opts)
+			dst := image.NewNRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+			draw.Draw(dst, inner, photo, photo.Bounds().Min, draw.Src)
+			return dst
+		}
+	}
+
+	if opts.ResizeMode == ResizeOriginal {
+		// Keep original resolution, but limit to 2048x2048
+		w, h := outputSize(width, height, ResizeOriginal)
+		if w == width && h == height {
+			// Return original image if no resize needed
+			return img
+		}
+		return imaging.Resize(img, w, h, imaging.Lanczos)
+	}
+
+	targetWidth, targetHeight := printSize(bounds)
+	return fitImage(img, targetWidth, targetHeight, opts.ResizeMode, opts)
+}
+
+// fitImage scales img to targetWidth x targetHeight in the given resize
+// mode, with the pad color and crop anchor in opts
+func fitImage(img image.Image, targetWidth, targetHeight int, mode ResizeMode, opts Options) image.Image {
+	switch mode {
+	case ResizeFit:
+		padColor := opts.PadColor
+		if padColor == nil {
+			padColor = DefaultPadColor
+		}
+		w, h := fitSize(img.Bounds().Dx(), img.Bounds().Dy(), targetWidth, targetHeight)
+		scaled := imaging.Resize(img, w, h, imaging.Lanczos)
+		return imaging.PasteCenter(imaging.New(targetWidth, targetHeight, padColor), scaled)
+
+	case ResizeFill:
+		return fillImage(img, targetWidth, targetHeight, opts.CropAnchor, opts.FocalPoint)
+
+	default:
+		// Resize to 1080p for prints (as per VRChat spec)
+		return imaging.Resize(img, targetWidth, targetHeight, imaging.Lanczos)
+	}
+}
+
+// photoSize returns the size resizeImage fits the photo of a width x height
+// image to: the print size, or the opening of the frame in opts
+func photoSize(width, height int, opts Options) (int, int) {
+	targetWidth, targetHeight := printSize(image.Rect(0, 0, width, height))
+	if opts.Frame != nil {
+		inner := opts.Frame.Border.inner(outputSize(width, height, opts.ResizeMode))
+		if !inner.Empty() {
+			return inner.Dx(), inner.Dy()
+		}
+	}
+	return targetWidth, targetHeight
+}
+
+// outputSize returns the dimensions resizeImage produces for an image of
+// width x height
+func outputSize(width, height int, mode ResizeMode) (int, int) {
+	if mode != ResizeOriginal {
+		return printSize(image.Rect(0, 0, width, height))
+	}
+	if width <= MaxResolution && height <= MaxResolution {
+		return width, height
+	}
+	// Scale the longer side down to MaxResolution, like imaging.Resize does
+	// when one dimension is 0
+	if width > height {
+		return MaxResolution, max(1, int(float64(MaxResolution)*float64(height)/float64(width)+0.5))
+	}
+	return max(1, int(float64(MaxResolution)*float64(width)/float64(height)+0.5)), MaxResolution
+}
+
+// fitSize returns the largest size with the aspect ratio of width x height
+// that fits inside maxWidth x maxHeight
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	w := int(math.Round(float64(width) * scale))
+	h := int(math.Round(float64(height) * scale))
+	return max(1, min(w, maxWidth)), max(1, min(h, maxHeight))
+}
@@ -0,0 +1,191 @@
+package upload
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden images in testdata/golden")
+
+func TestParseResizeMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    ResizeMode
+		expectError bool
+	}{
+		{name: "", expected: ResizeStretch},
+		{name: "stretch", expected: ResizeStretch},
+		{name: "Fit", expected: ResizeFit},
+		{name: "fill", expected: ResizeFill},
+		{name: "original", expected: ResizeOriginal},
+		{name: "zoom", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := ParseResizeMode(tt.name)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mode)
+		})
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, err := ParseHexColor("#ff8000")
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 255, G: 128, B: 0, A: 255}, c)
+
+	c, err = ParseHexColor("10203040")
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x40}, c)
+
+	_, err = ParseHexColor("#fff")
+	assert.Error(t, err)
+
+	_, err = ParseHexColor("#gggggg")
+	assert.Error(t, err)
+}
+
+func TestResizeImage_Modes(t *testing.T) {
+	pad := color.NRGBA{R: 32, G: 32, B: 64, A: 255}
+
+	tests := []struct {
+		name           string
+		width, height  int
+		mode           ResizeMode
+		expectedWidth  int
+		expectedHeight int
+		// Whether the top-left corner is letterbox padding
+		padded bool
+	}{
+		{name: "21:9 fill", width: 840, height: 360, mode: ResizeFill, expectedWidth: 1920, expectedHeight: 1080},
+		{name: "16:9 fit", width: 640, height: 360, mode: ResizeFit, expectedWidth: 1920, expectedHeight: 1080},
+		{name: "Portrait fit", width: 600, height: 800, mode: ResizeFit, expectedWidth: 1080, expectedHeight: 1920, padded: true},
+		{name: "Square fill", width: 500, height: 500, mode: ResizeFill, expectedWidth: 1080, expectedHeight: 1920},
+		{name: "Original", width: 500, height: 300, mode: ResizeOriginal, expectedWidth: 500, expectedHeight: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := createPatternImage(tt.width, tt.height)
+			result := resizeImage(src, Options{ResizeMode: tt.mode, PadColor: pad})
+
+			assert.Equal(t, tt.expectedWidth, result.Bounds().Dx())
+			assert.Equal(t, tt.expectedHeight, result.Bounds().Dy())
+
+			corner := color.NRGBAModel.Convert(result.At(result.Bounds().Min.X, result.Bounds().Min.Y)).(color.NRGBA)
+			if tt.padded {
+				assert.Equal(t, pad, corner)
+			} else {
+				assert.NotEqual(t, pad, corner)
+			}
+		})
+	}
+}
+
+func TestResizeImage_Golden(t *testing.T) {
+	pad := color.NRGBA{R: 32, G: 32, B: 64, A: 255}
+
+	tests := []struct {
+		name          string
+		width, height int
+		mode          ResizeMode
+		anchor        CropAnchor
+		focal         FocalPoint
+	}{
+		{name: "stretch_4x3", width: 400, height: 300, mode: ResizeStretch},
+		{name: "fit_4x3", width: 400, height: 300, mode: ResizeFit},
+		{name: "fill_4x3", width: 400, height: 300, mode: ResizeFill},
+		{name: "fill_4x3_focal_bottom", width: 400, height: 300, mode: ResizeFill, anchor: CropFocal, focal: FocalPoint{X: 0.5, Y: 1}},
+		{name: "fill_21x9_focal_left", width: 840, height: 360, mode: ResizeFill, anchor: CropFocal, focal: FocalPoint{X: 0.2, Y: 0.5}},
+		{name: "fit_21x9", width: 840, height: 360, mode: ResizeFit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{ResizeMode: tt.mode, PadColor: pad, CropAnchor: tt.anchor, FocalPoint: tt.focal}
+			result := resizeImage(createPatternImage(tt.width, tt.height), opts)
+			assertGolden(t, tt.name, result)
+		})
+	}
+}
+
+// createPatternImage creates an image with four solid quadrants and a
+// one-pixel frame, so distortion, cropping and padding are easy to spot
+func createPatternImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	quadrants := []color.NRGBA{
+		{R: 220, G: 40, B: 40, A: 255},
+		{R: 40, G: 180, B: 60, A: 255},
+		{R: 40, G: 80, B: 220, A: 255},
+		{R: 240, G: 220, B: 60, A: 255},
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := quadrants[(y*2/height)*2+x*2/width]
+			if x == 0 || y == 0 || x == width-1 || y == height-1 {
+				c = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// assertGolden compares img with testdata/golden/<name>.png. Run the tests
+// with -update to rewrite the golden images.
+func assertGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".png")
+
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		file, err := os.Create(path)
+		require.NoError(t, err)
+		defer file.Close()
+		require.NoError(t, (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(file, img))
+		return
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err, "missing golden image, run tests with -update")
+	defer file.Close()
+
+	expected, err := png.Decode(file)
+	require.NoError(t, err)
+	require.Equal(t, expected.Bounds(), img.Bounds())
+
+	// Allow tiny differences from floating point rounding across platforms.
+	// Both images are compared as NRGBA pixels; converting them pixel by
+	// pixel is slow for print-sized images.
+	const tolerance = 2
+	e, a := imaging.Clone(expected), imaging.Clone(img)
+	for i := range a.Pix {
+		if absDiff(e.Pix[i], a.Pix[i]) > tolerance {
+			x, y := i%a.Stride/4, i/a.Stride
+			t.Fatalf("pixel (%d, %d) differs from golden image %s: expected %v, got %v", x, y, path, e.NRGBAAt(x, y), a.NRGBAAt(x, y))
+		}
+	}
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,184 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// KeywordXMP is the iTXt keyword of an XMP packet
+const KeywordXMP = "XML:com.adobe.xmp"
+
+// screenshotNamePattern matches VRChat screenshot filenames, both
+// VRChat_2024-03-15_21-30-05.123_1920x1080.png and the older
+// VRChat_1920x1080_2024-03-15_21-30-05.123.png
+var screenshotNamePattern = regexp.MustCompile(`^VRChat_(?:\d+x\d+_)?(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}\.\d{3})`)
+
+// screenshotTimeLayout is the time in screenshot filenames, in local time
+const screenshotTimeLayout = "2006-01-02_15-04-05.000"
+
+// ExtractMetadata reads what is known about a screenshot before it is
+// uploaded: the XMP metadata embedded by VRChat, the text chunks written by
+// Upload and, failing those, the capture time in a VRChat screenshot
+// filename. Fields that can't be found are left empty.
+func ExtractMetadata(path string) (*Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	m := &Metadata{}
+	// Only PNGs carry text chunks; anything else falls back to the filename
+	if text, err := readPNGText(file); err == nil {
+		m = metadataFromText(text)
+		if packet := text[KeywordXMP]; packet != "" {
+			m.merge(parseXMP([]byte(packet)))
+		}
+	}
+
+	if m.CaptureTime.IsZero() {
+		if t, ok := ParseScreenshotFilename(filepath.Base(path)); ok {
+			m.CaptureTime = t
+		}
+	}
+	return m, nil
+}
+
+// ParseScreenshotFilename returns the capture time in the name of a VRChat
+// screenshot. VRChat names screenshots in local time.
+func ParseScreenshotFilename(name string) (time.Time, bool) {
+	match := screenshotNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(screenshotTimeLayout, match[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// merge fills the empty fields of m from other
+func (m *Metadata) merge(other Metadata) {
+	if m.Note == "" {
+		m.Note = other.Note
+	}
+	if m.WorldID == "" {
+		m.WorldID = other.WorldID
+	}
+	if m.WorldName == "" {
+		m.WorldName = other.WorldName
+	}
+	if m.CaptureTime.IsZero() {
+		m.CaptureTime = other.CaptureTime
+	}
+	if m.SourceFile == "" {
+		m.SourceFile = other.SourceFile
+	}
+	if m.Author == "" {
+		m.Author = other.Author
+	}
+	if m.Software == "" {
+		m.Software = other.Software
+	}
+}
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// xmpProperties maps XMP property names to metadata fields, preferred
+// properties first. VRChat writes the world to its own vrc namespace and
+// mirrors the world name into dc:title.
+var xmpProperties = []struct {
+	names []string
+	set   func(m *Metadata, value string)
+}{
+	{[]string{"WorldID"}, func(m *Metadata, v string) { m.WorldID = v }},
+	{[]string{"WorldDisplayName", "title"}, func(m *Metadata, v string) { m.WorldName = v }},
+	{[]string{"Author", "creator"}, func(m *Metadata, v string) { m.Author = v }},
+	{[]string{"CreateDate", "DateTimeOriginal"}, func(m *Metadata, v string) {
+		m.CaptureTime = parseXMPDate(v)
+	}},
+	{[]string{"CreatorTool"}, func(m *Metadata, v string) { m.Software = v }},
+}
+
+// parseXMP reads the metadata fields from an XMP packet. Properties are
+// matched by name whatever their namespace, in both element and attribute
+// form; a malformed packet yields whatever was read before the error.
+func parseXMP(packet []byte) Metadata {
+	values := map[string]string{}
+	set := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" && values[name] == "" {
+			values[name] = value
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	// properties holds the enclosing property names; RDF container elements
+	// like rdf:Alt and rdf:li are recorded as empty so that their text
+	// belongs to the property around them
+	var properties []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != rdfNamespace {
+				properties = append(properties, t.Name.Local)
+				break
+			}
+			properties = append(properties, "")
+			if t.Name.Local == "Description" {
+				for _, attr := range t.Attr {
+					if attr.Name.Space != rdfNamespace && attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+						set(attr.Name.Local, attr.Value)
+					}
+				}
+			}
+		case xml.EndElement:
+			if len(properties) > 0 {
+				properties = properties[:len(properties)-1]
+			}
+		case xml.CharData:
+			for i := len(properties) - 1; i >= 0; i-- {
+				if properties[i] != "" {
+					set(properties[i], string(t))
+					break
+				}
+			}
+		}
+	}
+
+	var m Metadata
+	for _, property := range xmpProperties {
+		for _, name := range property.names {
+			if value := values[name]; value != "" {
+				property.set(&m, value)
+				break
+			}
+		}
+	}
+	return m
+}
+
+// parseXMPDate parses an XMP date, which may leave out the seconds and the
+// time zone. Dates without a time zone are local.
+func parseXMPDate(s string) time.Time {
+	if t := parseTime(s, time.RFC3339Nano, "2006-01-02T15:04Z07:00"); !t.IsZero() {
+		return t
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
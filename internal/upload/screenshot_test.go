@@ -0,0 +1,167 @@
+package upload
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vrchatXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:vrc="http://ns.vrchat.com/vrc/1.0/">
+   <xmp:CreatorTool>VRChat</xmp:CreatorTool>
+   <xmp:Author>yoshiken</xmp:Author>
+   <xmp:CreateDate>2024-03-15T21:30:05.123+09:00</xmp:CreateDate>
+   <dc:title>
+    <rdf:Alt>
+     <rdf:li xml:lang="x-default">日本の街</rdf:li>
+    </rdf:Alt>
+   </dc:title>
+   <vrc:WorldID>wrld_12345678-1234-1234-1234-123456789012</vrc:WorldID>
+   <vrc:WorldDisplayName>日本の街</vrc:WorldDisplayName>
+   <vrc:AuthorID>usr_12345678-1234-1234-1234-123456789012</vrc:AuthorID>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// writeScreenshot writes a small PNG with the given text chunks
+func writeScreenshot(t *testing.T, path string, text map[string]string) {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, createPatternImage(4, 4)))
+
+	var chunks bytes.Buffer
+	for keyword, value := range text {
+		writePNGChunk(&chunks, "iTXt", []byte(keyword+"\x00\x00\x00\x00\x00"+value))
+	}
+	require.NoError(t, os.WriteFile(path, embedChunks(encoded.Bytes(), chunks.Bytes()), 0600))
+}
+
+func TestExtractMetadata(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	filenameTime := time.Date(2024, 3, 15, 21, 30, 5, 123000000, time.Local)
+
+	tests := []struct {
+		name     string
+		filename string
+		text     map[string]string
+		expected Metadata
+	}{
+		{
+			name:     "VRChat XMP",
+			filename: "VRChat_2024-01-01_00-00-00.000_1920x1080.png",
+			text:     map[string]string{KeywordXMP: vrchatXMP},
+			expected: Metadata{
+				WorldID:     "wrld_12345678-1234-1234-1234-123456789012",
+				WorldName:   "日本の街",
+				CaptureTime: time.Date(2024, 3, 15, 21, 30, 5, 123000000, jst),
+				Author:      "yoshiken",
+				Software:    "VRChat",
+			},
+		},
+		{
+			name:     "XMP attributes",
+			filename: "photo.png",
+			text: map[string]string{KeywordXMP: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+				`<rdf:Description xmlns:vrc="http://ns.vrchat.com/vrc/1.0/" vrc:WorldID="wrld_attr" vrc:WorldDisplayName="Attribute World"/>` +
+				`</rdf:RDF></x:xmpmeta>`},
+			expected: Metadata{WorldID: "wrld_attr", WorldName: "Attribute World"},
+		},
+		{
+			name:     "Print text chunks",
+			filename: "print.png",
+			text: map[string]string{
+				KeywordWorldID:     "wrld_print",
+				KeywordWorldName:   "Print World",
+				KeywordCaptureTime: "2024-03-15T21:30:05+09:00",
+				KeywordAuthor:      "よしけん",
+				// Text chunks win over XMP
+				KeywordXMP: vrchatXMP,
+			},
+			expected: Metadata{
+				WorldID:     "wrld_print",
+				WorldName:   "Print World",
+				CaptureTime: time.Date(2024, 3, 15, 21, 30, 5, 0, jst),
+				Author:      "よしけん",
+				Software:    "VRChat",
+			},
+		},
+		{
+			name:     "Filename",
+			filename: "VRChat_2024-03-15_21-30-05.123_1920x1080.png",
+			expected: Metadata{CaptureTime: filenameTime},
+		},
+		{
+			name:     "Old filename",
+			filename: "VRChat_1920x1080_2024-03-15_21-30-05.123.png",
+			expected: Metadata{CaptureTime: filenameTime},
+		},
+		{
+			name:     "Broken XMP",
+			filename: "VRChat_2024-03-15_21-30-05.123_1920x1080.png",
+			text:     map[string]string{KeywordXMP: `<x:xmpmeta><rdf:Description><vrc:WorldID>wrld_partial</vrc:WorldID><unclosed`},
+			expected: Metadata{WorldID: "wrld_partial", CaptureTime: filenameTime},
+		},
+		{
+			name:     "Nothing",
+			filename: "photo.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.filename)
+			writeScreenshot(t, path, tt.text)
+
+			m, err := ExtractMetadata(path)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.CaptureTime.Equal(m.CaptureTime), "capture time %v", m.CaptureTime)
+			m.CaptureTime = tt.expected.CaptureTime
+			assert.Equal(t, tt.expected, *m)
+		})
+	}
+}
+
+func TestExtractMetadata_NotPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "VRChat_2024-03-15_21-30-05.123_1920x1080.jpg")
+	require.NoError(t, createTestImage(path, "jpeg", 16, 16))
+
+	m, err := ExtractMetadata(path)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 15, 21, 30, 5, 123000000, time.Local), m.CaptureTime)
+
+	_, err = ExtractMetadata(filepath.Join(t.TempDir(), "missing.png"))
+	assert.ErrorContains(t, err, "failed to open image file")
+}
+
+func TestParseScreenshotFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected time.Time
+		ok       bool
+	}{
+		{"VRChat_2024-03-15_21-30-05.123_1920x1080.png", time.Date(2024, 3, 15, 21, 30, 5, 123000000, time.Local), true},
+		{"VRChat_3840x2160_2023-12-31_23-59-59.999.png", time.Date(2023, 12, 31, 23, 59, 59, 999000000, time.Local), true},
+		{"VRChat_2024-13-45_21-30-05.123_1920x1080.png", time.Time{}, false},
+		{"Screenshot_2024-03-15_21-30-05.123.png", time.Time{}, false},
+		{"photo.png", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseScreenshotFilename(tt.name)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package upload
+
+import (
+	_ "embed"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// mplusTTF is M+ 1p, which covers Japanese as well as Latin text. See
+// fonts/LICENSE-mplus.txt.
+//
+//go:embed fonts/mplus-1p-regular.ttf
+var mplusTTF []byte
+
+// embeddedFont parses a font compiled into the binary on first use
+type embeddedFont struct {
+	data []byte
+	once sync.Once
+	font *opentype.Font
+	err  error
+}
+
+var (
+	goRegularFont = &embeddedFont{data: goregular.TTF}
+	mplusFont     = &embeddedFont{data: mplusTTF}
+)
+
+func (f *embeddedFont) load() (*opentype.Font, error) {
+	f.once.Do(func() {
+		f.font, f.err = opentype.Parse(f.data)
+	})
+	return f.font, f.err
+}
+
+// TextAlign aligns lines of text horizontally
+type TextAlign string
+
+const (
+	AlignLeft   TextAlign = "left"
+	AlignCenter TextAlign = "center"
+	AlignRight  TextAlign = "right"
+)
+
+// drawText draws lines of text in f onto a transparent image, with
+// lineHeight pixels per line. It returns nil if there is nothing to draw.
+func drawText(f *opentype.Font, lines []string, lineHeight float64, c color.Color, align TextAlign) image.Image {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		// Leave room between lines
+		Size:    lineHeight / 1.2,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil
+	}
+	defer face.Close()
+
+	lines = dropMissingGlyphs(f, lines)
+	metrics := face.Metrics()
+	height := metrics.Height.Ceil()
+	widths := make([]int, len(lines))
+	width := 0
+	for i, line := range lines {
+		widths[i] = font.MeasureString(face, line).Ceil()
+		width = max(width, widths[i])
+	}
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height*len(lines)))
+	drawer := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	for i, line := range lines {
+		x := 0
+		switch align {
+		case AlignCenter:
+			x = (width - widths[i]) / 2
+		case AlignRight:
+			x = width - widths[i]
+		}
+		drawer.Dot = fixed.Point26_6{X: fixed.I(x), Y: fixed.I(height*i) + metrics.Ascent}
+		drawer.DrawString(line)
+	}
+	return dst
+}
+
+// dropMissingGlyphs removes the characters f has no glyph for, such as
+// emoji, which would otherwise be drawn as boxes
+func dropMissingGlyphs(f *opentype.Font, lines []string) []string {
+	var buf sfnt.Buffer
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.Map(func(r rune) rune {
+			if index, err := f.GlyphIndex(&buf, r); err != nil || (index == 0 && !unicode.IsSpace(r)) {
+				return -1
+			}
+			return r
+		}, line)
+	}
+	return out
+}
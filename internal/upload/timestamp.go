@@ -0,0 +1,80 @@
+package upload
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimestampSource selects the timestamp of a print when Options.Timestamp
+// isn't set
+type TimestampSource string
+
+const (
+	// TimestampNow uses the time of the upload
+	TimestampNow TimestampSource = "now"
+	// TimestampCapture uses the capture time of the image, falling back to
+	// the modification time of the file
+	TimestampCapture TimestampSource = "capture"
+	// TimestampModified uses the modification time of the file
+	TimestampModified TimestampSource = "modified"
+)
+
+// ParseTimestampSource parses a timestamp source name. An empty name selects
+// TimestampNow.
+func ParseTimestampSource(name string) (TimestampSource, error) {
+	switch source := TimestampSource(strings.ToLower(strings.TrimSpace(name))); source {
+	case "":
+		return TimestampNow, nil
+	case TimestampNow, TimestampCapture, TimestampModified:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unknown timestamp source: %s", name)
+	}
+}
+
+// timestampLayouts are accepted by ParseTimestamp for times without a UTC
+// offset
+var timestampLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// ParseTimestamp parses a time in RFC 3339 notation, or without a UTC offset
+// in loc, as entered in date and time inputs. A nil loc means local time.
+func ParseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp: %s", s)
+}
+
+// timestamp returns the timestamp sent with the print: Timestamp if set,
+// otherwise the time selected by TimestampSource, or now if that is unknown.
+// It is expressed in TimeZone if set.
+func (opts Options) timestamp() time.Time {
+	t := opts.Timestamp
+	if t.IsZero() {
+		switch opts.TimestampSource {
+		case TimestampCapture:
+			t = captureTime(opts)
+		case TimestampModified:
+			t = modTime(opts.ImagePath)
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	if opts.TimeZone != nil {
+		t = t.In(opts.TimeZone)
+	}
+	return t
+}
@@ -0,0 +1,167 @@
+package upload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestampSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected TimestampSource
+		wantErr  bool
+	}{
+		{"", TimestampNow, false},
+		{"now", TimestampNow, false},
+		{" Capture ", TimestampCapture, false},
+		{"modified", TimestampModified, false},
+		{"exif", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := ParseTimestampSource(tt.name)
+			if tt.wantErr {
+				assert.EqualError(t, err, "unknown timestamp source: "+tt.name)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, source)
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name     string
+		input    string
+		loc      *time.Location
+		expected time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "RFC 3339 keeps its offset",
+			input:    "2024-03-15T21:30:05-05:00",
+			loc:      tokyo,
+			expected: time.Date(2024, 3, 15, 21, 30, 5, 0, time.FixedZone("", -5*60*60)),
+		},
+		{
+			name:     "Without offset",
+			input:    "2024-03-15T21:30:05",
+			loc:      tokyo,
+			expected: time.Date(2024, 3, 15, 21, 30, 5, 0, tokyo),
+		},
+		{
+			name:     "Without seconds",
+			input:    "2024-03-15T21:30",
+			loc:      tokyo,
+			expected: time.Date(2024, 3, 15, 21, 30, 0, 0, tokyo),
+		},
+		{
+			name:     "Local",
+			input:    "2024-03-15 21:30:05",
+			expected: time.Date(2024, 3, 15, 21, 30, 5, 0, time.Local),
+		},
+		{
+			name:    "Invalid",
+			input:   "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimestamp(tt.input, tt.loc)
+			if tt.wantErr {
+				assert.EqualError(t, err, "invalid timestamp: "+tt.input)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(got), "got %v", got)
+			_, expectedOffset := tt.expected.Zone()
+			_, offset := got.Zone()
+			assert.Equal(t, expectedOffset, offset)
+		})
+	}
+}
+
+func TestOptions_Timestamp(t *testing.T) {
+	dir := t.TempDir()
+	modified := time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC)
+
+	plain := filepath.Join(dir, "photo.png")
+	require.NoError(t, createTestImage(plain, "png", 8, 8))
+	require.NoError(t, os.Chtimes(plain, modified, modified))
+
+	screenshot := filepath.Join(dir, "VRChat_2024-03-15_21-30-05.123_1920x1080.png")
+	require.NoError(t, createTestImage(screenshot, "png", 8, 8))
+	require.NoError(t, os.Chtimes(screenshot, modified, modified))
+
+	explicit := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected time.Time
+	}{
+		{
+			name:     "Explicit",
+			opts:     Options{ImagePath: screenshot, Timestamp: explicit, TimestampSource: TimestampCapture},
+			expected: explicit,
+		},
+		{
+			name:     "Capture time from the filename",
+			opts:     Options{ImagePath: screenshot, TimestampSource: TimestampCapture},
+			expected: time.Date(2024, 3, 15, 21, 30, 5, 123000000, time.Local),
+		},
+		{
+			name:     "Given capture time",
+			opts:     Options{ImagePath: screenshot, CaptureTime: explicit, TimestampSource: TimestampCapture},
+			expected: explicit,
+		},
+		{
+			name:     "Capture time falls back to the modification time",
+			opts:     Options{ImagePath: plain, TimestampSource: TimestampCapture},
+			expected: modified,
+		},
+		{
+			name:     "Modification time",
+			opts:     Options{ImagePath: screenshot, TimestampSource: TimestampModified},
+			expected: modified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.opts.timestamp()
+			assert.True(t, tt.expected.Equal(got), "got %v", got)
+
+			// The time zone changes the notation, not the instant
+			tt.opts.TimeZone = tokyo
+			inTokyo := tt.opts.timestamp()
+			assert.True(t, tt.expected.Equal(inTokyo))
+			assert.Equal(t, tt.expected.In(tokyo).Format(time.RFC3339), inTokyo.Format(time.RFC3339))
+		})
+	}
+
+	t.Run("Now", func(t *testing.T) {
+		for _, opts := range []Options{
+			{ImagePath: plain},
+			{ImagePath: filepath.Join(dir, "missing.png"), TimestampSource: TimestampModified},
+			{ImagePath: filepath.Join(dir, "missing.png"), TimestampSource: TimestampCapture},
+		} {
+			before := time.Now()
+			got := opts.timestamp()
+			assert.False(t, got.Before(before))
+			assert.False(t, got.After(time.Now()))
+		}
+	})
+}
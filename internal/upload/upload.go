@@ -1,18 +1,15 @@
 package upload
 
 import (
-	"bytes"
+	"bufio"
+	"context"
 	"fmt"
 	"image"
-	"image/png"
+	"image/color"
 	"io"
-	"mime/multipart"
-	"net/textproto"
 	"os"
-	"path/filepath"
 	"time"
 
-	"github.com/disintegration/imaging"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -35,7 +32,57 @@ type Options struct {
 	Note      string
 	WorldID   string
 	WorldName string
-	NoResize  bool
+	// CaptureTime is when the image was taken, as shown in the frame
+	// caption and written into the metadata; zero means the time embedded
+	// in the image or its VRChat filename, or else its modification time
+	CaptureTime time.Time
+	// Timestamp is the date of the print. Zero selects it by
+	// TimestampSource; an empty source means the time of the upload.
+	Timestamp       time.Time
+	TimestampSource TimestampSource
+	// TimeZone, if set, is the zone the timestamp is sent in; otherwise it
+	// keeps its own zone, which is local for file and upload times
+	TimeZone *time.Location
+	// ResizeMode selects how the image is fitted to the print resolution;
+	// empty means ResizeStretch
+	ResizeMode ResizeMode
+	// PadColor fills the letterbox area in ResizeFit mode; nil means black
+	PadColor color.Color
+	// CropAnchor selects the region kept in ResizeFill mode; empty means
+	// CropCenter
+	CropAnchor CropAnchor
+	// FocalPoint is the crop center for CropFocal
+	FocalPoint FocalPoint
+	// Rotate, FlipHorizontal and FlipVertical are applied after the EXIF
+	// orientation and before resizing: first the rotation, then the flips
+	Rotate         Rotation
+	FlipHorizontal bool
+	FlipVertical   bool
+	// Filters are applied in order after the orientation and before the
+	// image is fitted to the print size
+	Filters []Filter
+	// PreserveAlpha keeps the transparency of the image instead of
+	// flattening it onto Background
+	PreserveAlpha bool
+	// Background fills transparent areas; nil means DefaultBackground
+	Background color.Color
+	// Frame, if set, is drawn around the image after it is fitted to the
+	// print size. Its caption shows Note, WorldName and the capture date.
+	Frame *FrameTemplate
+	// Watermarks are stamped in order after the frame
+	Watermarks []Watermark
+	// Limits caps the dimensions of images that are decoded
+	Limits Limits
+	// Output controls how the PNG is shrunk to fit the size limit
+	Output OutputOptions
+	// Metadata controls the provenance written into the PNG
+	Metadata MetadataOptions
+	// ExportDir, if set, makes Upload a dry run: the prepared PNG and a JSON
+	// manifest of the form fields are written to the directory, next to
+	// earlier exports rather than over them, and nothing is sent
+	ExportDir string
+	// Progress, if set, is called as the image is prepared and sent
+	Progress ProgressFunc
 }
 
 type UploadResult struct {
@@ -45,111 +92,57 @@ type UploadResult struct {
 	CreatedAt  time.Time `json:"createdAt"`
 	WorldID    string    `json:"worldId"`
 	WorldName  string    `json:"worldName"`
+	// Encoding reports how the uploaded PNG was produced
+	Encoding *EncodeResult `json:"-"`
+	// ExportPath and ManifestPath are the files written by a dry run
+	ExportPath   string `json:"-"`
+	ManifestPath string `json:"-"`
 }
 
+// New returns an Uploader that sends with client. Only a client built by
+// client.New streams the form; others read it into memory first.
 func New(client *resty.Client) *Uploader {
 	return &Uploader{
 		client: client,
 	}
 }
 
-// createMultipartForm creates a multipart form with image data and metadata
-func (u *Uploader) createMultipartForm(imageData []byte, opts Options) (*bytes.Buffer, string, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Add image file with explicit content type
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="image"; filename="%s"`, filepath.Base(opts.ImagePath)))
-	h.Set("Content-Type", "image/png")
-	
-	part, err := writer.CreatePart(h)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create form part: %w", err)
-	}
-	
-	if _, err := io.Copy(part, bytes.NewReader(imageData)); err != nil {
-		return nil, "", fmt.Errorf("failed to write image data: %w", err)
-	}
-
-	// Add timestamp
-	if err := writer.WriteField("timestamp", time.Now().Format(time.RFC3339)); err != nil {
-		return nil, "", fmt.Errorf("failed to write timestamp: %w", err)
-	}
-
-	// Add optional fields
-	if opts.Note != "" {
-		if err := writer.WriteField("note", opts.Note); err != nil {
-			return nil, "", fmt.Errorf("failed to write note: %w", err)
-		}
-	}
-
-	if opts.WorldID != "" {
-		if err := writer.WriteField("worldId", opts.WorldID); err != nil {
-			return nil, "", fmt.Errorf("failed to write worldId: %w", err)
-		}
+func (u *Uploader) Upload(ctx context.Context, opts Options) (*UploadResult, error) {
+	if opts.ExportDir != "" {
+		return Export(ctx, opts)
 	}
 
-	if opts.WorldName != "" {
-		if err := writer.WriteField("worldName", opts.WorldName); err != nil {
-			return nil, "", fmt.Errorf("failed to write worldName: %w", err)
-		}
-	}
+	// Read the capture time once for the frame, the metadata and the
+	// timestamp
+	opts.CaptureTime = captureTime(opts)
 
-	contentType := writer.FormDataContentType()
-	if err := writer.Close(); err != nil {
-		return nil, "", fmt.Errorf("failed to close multipart writer: %w", err)
-	}
-
-	return body, contentType, nil
-}
-
-// resizeImage resizes the image according to the specified options
-func resizeImage(img image.Image, noResize bool) image.Image {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	if !noResize {
-		// Resize to 1080p for prints (as per VRChat spec)
-		// 1920x1080 or 1080x1920 depending on orientation
-		if width > height {
-			return imaging.Resize(img, Print1080pWidth, Print1080pHeight, imaging.Lanczos)
-		} else {
-			return imaging.Resize(img, Print1080pHeight, Print1080pWidth, imaging.Lanczos)
-		}
-	} else {
-		// Keep original resolution when noResize is true, but limit to 2048x2048
-		if width > MaxResolution || height > MaxResolution {
-			// Resize to fit within MaxResolution while maintaining aspect ratio
-			if width > height {
-				return imaging.Resize(img, MaxResolution, 0, imaging.Lanczos)
-			} else {
-				return imaging.Resize(img, 0, MaxResolution, imaging.Lanczos)
-			}
-		}
-		// Return original image if no resize needed
-		return img
-	}
-}
-
-func (u *Uploader) Upload(opts Options) (*UploadResult, error) {
 	// Validate and prepare image
-	imageData, err := u.prepareImage(opts.ImagePath, opts.NoResize)
+	imageData, encoding, err := u.prepareImage(ctx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare image: %w", err)
 	}
 
-	// Create multipart form
-	body, contentType, err := u.createMultipartForm(imageData, opts)
+	result, err := u.send(ctx, imageData, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Encoding = encoding
+	return result, nil
+}
+
+// send posts imageData and the metadata in opts as a multipart form that is
+// streamed into the request
+func (u *Uploader) send(ctx context.Context, imageData []byte, opts Options) (*UploadResult, error) {
+	body, err := newMultipartBody(imageData, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create multipart form: %w", err)
 	}
 
 	// Upload
 	resp, err := u.client.R().
-		SetHeader("Content-Type", contentType).
-		SetBody(body.Bytes()).
+		SetContext(ctx).
+		SetHeader("Content-Type", body.ContentType()).
+		SetBody(body).
 		SetResult(&UploadResult{}).
 		Post(UploadEndpoint)
 
@@ -164,7 +157,44 @@ func (u *Uploader) Upload(opts Options) (*UploadResult, error) {
 	return resp.Result().(*UploadResult), nil
 }
 
-func (u *Uploader) prepareImage(imagePath string, noResize bool) ([]byte, error) {
+func (u *Uploader) prepareImage(ctx context.Context, opts Options) ([]byte, *EncodeResult, error) {
+	progress := newProgressReporter(opts.Progress)
+
+	if err := validateProcessing(opts); err != nil {
+		return nil, nil, err
+	}
+
+	img, err := loadImage(ctx, opts.ImagePath, opts.Limits, progress)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkFilterSize(opts, img.Bounds().Dx(), img.Bounds().Dy()); err != nil {
+		return nil, nil, err
+	}
+
+	steps, err := uploadPipeline(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Process the image, fit it to the print size and stamp the watermarks
+	progress.begin(PhaseResize, 0)
+	img, err = steps.Apply(ctx, img)
+	if err != nil {
+		return nil, nil, err
+	}
+	progress.finish()
+
+	// Encode as PNG with the metadata, shrinking it to fit the size limit if
+	// necessary
+	return encodeOutput(ctx, img, opts.Output, opts.metadata(), progress)
+}
+
+// loadImage checks the size and declared dimensions of the image file at
+// imagePath and decodes it, reporting the decode phase to progress. JPEGs
+// are rotated or flipped upright according to their EXIF orientation, and
+// PNGs are corrected for the gamma in their gAMA chunk.
+func loadImage(ctx context.Context, imagePath string, limits Limits, progress *progressReporter) (image.Image, error) {
 	// Check file exists
 	info, err := os.Stat(imagePath)
 	if err != nil {
@@ -183,26 +213,69 @@ func (u *Uploader) prepareImage(imagePath string, noResize bool) ([]byte, error)
 	}
 	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	// Check the declared dimensions before the decoder allocates anything
+	cfg, _, err := image.DecodeConfig(bufio.NewReader(file))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
+	if err := limits.check(cfg); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read image file: %w", err)
+	}
 
-	// Resize image according to options
-	img = resizeImage(img, noResize)
+	progress.begin(PhaseDecode, info.Size())
+	img, format, err := image.Decode(&contextReader{ctx: ctx, r: &progressReader{r: file, reporter: progress}})
+	if err != nil {
+		// Decoders may hide the read error behind a format error
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	progress.finish()
 
-	// Encode as PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, fmt.Errorf("failed to encode image as PNG: %w", err)
+	switch format {
+	case "jpeg":
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read image file: %w", err)
+		}
+		img = applyEXIFOrientation(img, readEXIFOrientation(file))
+	case "png":
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read image file: %w", err)
+		}
+		img = applyPNGGamma(img, readPNGColorInfo(file))
 	}
 
-	// Check final size
-	if buf.Len() > MaxImageSize {
-		return nil, fmt.Errorf("encoded image too large: %d bytes (max: %d bytes)", buf.Len(), MaxImageSize)
+	return img, nil
+}
+
+// contextReader stops reading once ctx is cancelled, so that decoding a
+// large image can be aborted
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
+	return r.r.Read(p)
+}
 
-	// Image prepared and converted to PNG
-	
-	return buf.Bytes(), nil
-}
\ No newline at end of file
+// contextWriter stops writing once ctx is cancelled, so that encoding a
+// large image can be aborted
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
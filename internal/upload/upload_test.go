@@ -2,6 +2,7 @@ package upload
 
 import (
 	"bytes"
+	"context"
 	"image"
 	"image/color"
 	"image/gif"
@@ -28,7 +29,7 @@ func TestPrepareImage(t *testing.T) {
 	tests := []struct {
 		name          string
 		createImage   func(string) error
-		noResize      bool
+		resizeMode    ResizeMode
 		expectError   bool
 		expectedMsg   string
 		checkResult   func(*testing.T, []byte)
@@ -38,7 +39,7 @@ func TestPrepareImage(t *testing.T) {
 			createImage: func(path string) error {
 				return createTestImage(path, "png", 1000, 800)
 			},
-			noResize: true,
+			resizeMode: ResizeOriginal,
 			checkResult: func(t *testing.T, data []byte) {
 				// Verify it's a valid PNG
 				img, format, err := image.Decode(bytes.NewReader(data))
@@ -53,7 +54,7 @@ func TestPrepareImage(t *testing.T) {
 			createImage: func(path string) error {
 				return createTestImage(path, "png", 1000, 800)
 			},
-			noResize: false,
+			resizeMode: ResizeStretch,
 			checkResult: func(t *testing.T, data []byte) {
 				img, format, err := image.Decode(bytes.NewReader(data))
 				require.NoError(t, err)
@@ -68,7 +69,7 @@ func TestPrepareImage(t *testing.T) {
 			createImage: func(path string) error {
 				return createTestImage(path, "jpeg", 800, 600)
 			},
-			noResize: false,
+			resizeMode: ResizeStretch,
 			checkResult: func(t *testing.T, data []byte) {
 				img, format, err := image.Decode(bytes.NewReader(data))
 				require.NoError(t, err)
@@ -82,7 +83,7 @@ func TestPrepareImage(t *testing.T) {
 			createImage: func(path string) error {
 				return createTestImage(path, "png", 800, 1200)
 			},
-			noResize: false,
+			resizeMode: ResizeStretch,
 			checkResult: func(t *testing.T, data []byte) {
 				img, _, err := image.Decode(bytes.NewReader(data))
 				require.NoError(t, err)
@@ -96,7 +97,7 @@ func TestPrepareImage(t *testing.T) {
 			createImage: func(path string) error {
 				return createTestImage(path, "png", 3000, 4000)
 			},
-			noResize: true,
+			resizeMode: ResizeOriginal,
 			checkResult: func(t *testing.T, data []byte) {
 				img, _, err := image.Decode(bytes.NewReader(data))
 				require.NoError(t, err)
@@ -113,7 +114,7 @@ func TestPrepareImage(t *testing.T) {
 			createImage: func(path string) error {
 				return createTestImage(path, "gif", 500, 500)
 			},
-			noResize: true,
+			resizeMode: ResizeOriginal,
 			checkResult: func(t *testing.T, data []byte) {
 				img, format, err := image.Decode(bytes.NewReader(data))
 				require.NoError(t, err)
@@ -129,7 +130,7 @@ func TestPrepareImage(t *testing.T) {
 				os.Remove(path)
 				return nil
 			},
-			noResize:    false,
+			resizeMode:  ResizeStretch,
 			expectError: true,
 			expectedMsg: "failed to stat image file",
 		},
@@ -152,7 +153,7 @@ func TestPrepareImage(t *testing.T) {
 			}
 
 			uploader := &Uploader{}
-			data, err := uploader.prepareImage(imagePath, tt.noResize)
+			data, _, err := uploader.prepareImage(context.Background(), Options{ImagePath: imagePath, ResizeMode: tt.resizeMode})
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -240,10 +241,10 @@ func TestUpload(t *testing.T) {
 			},
 		},
 		{
-			name: "Upload with no-resize",
+			name: "Upload with original size",
 			opts: Options{
-				ImagePath: imagePath,
-				NoResize:  true,
+				ImagePath:  imagePath,
+				ResizeMode: ResizeOriginal,
 			},
 		},
 		{
@@ -257,7 +258,7 @@ func TestUpload(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := uploader.Upload(tt.opts)
+			result, err := uploader.Upload(context.Background(), tt.opts)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -322,7 +323,7 @@ func TestUploadErrorResponses(t *testing.T) {
 			client.SetBaseURL("https://api.vrchat.cloud/api/1")
 			uploader := New(client)
 
-			result, err := uploader.Upload(Options{
+			result, err := uploader.Upload(context.Background(), Options{
 				ImagePath: imagePath,
 			})
 
@@ -333,6 +334,88 @@ func TestUploadErrorResponses(t *testing.T) {
 	}
 }
 
+func TestUploadCancelled(t *testing.T) {
+	// Create temp directory for test images
+	tempDir, err := os.MkdirTemp("", "vrc-print-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	imagePath := filepath.Join(tempDir, "test.png")
+	err = createTestImage(imagePath, "png", 100, 100)
+	require.NoError(t, err)
+
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.vrchat.cloud/api/1/prints",
+		httpmock.NewStringResponder(200, `{"fileId": "file_12345"}`))
+
+	client.SetBaseURL("https://api.vrchat.cloud/api/1")
+	uploader := New(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := uploader.Upload(ctx, Options{
+		ImagePath: imagePath,
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+}
+
+func TestUploadProgress(t *testing.T) {
+	// Create temp directory for test images
+	tempDir, err := os.MkdirTemp("", "vrc-print-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	imagePath := filepath.Join(tempDir, "test.png")
+	err = createTestImage(imagePath, "png", 400, 300)
+	require.NoError(t, err)
+
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.vrchat.cloud/api/1/prints",
+		func(req *http.Request) (*http.Response, error) {
+			if err := req.ParseMultipartForm(32 << 20); err != nil {
+				return httpmock.NewStringResponse(400, "Invalid multipart form"), nil
+			}
+			return httpmock.NewJsonResponse(200, &UploadResult{FileID: "file_12345"})
+		})
+
+	client.SetBaseURL("https://api.vrchat.cloud/api/1")
+	uploader := New(client)
+
+	var updates []Progress
+	_, err = uploader.Upload(context.Background(), Options{
+		ImagePath: imagePath,
+		Progress: func(p Progress) {
+			updates = append(updates, p)
+		},
+	})
+	require.NoError(t, err)
+
+	// Phases are reported in pipeline order
+	var phases []Phase
+	for _, p := range updates {
+		if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+			phases = append(phases, p.Phase)
+		}
+	}
+	assert.Equal(t, []Phase{PhaseDecode, PhaseResize, PhaseEncode, PhaseUpload}, phases)
+
+	// The last update covers the whole request body
+	last := updates[len(updates)-1]
+	assert.Equal(t, PhaseUpload, last.Phase)
+	assert.Greater(t, last.Total, int64(0))
+	assert.Equal(t, last.Total, last.BytesSent)
+}
+
 // Helper function to create test images
 func createTestImage(path string, format string, width, height int) error {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
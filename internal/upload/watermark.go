@@ -0,0 +1,243 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// WatermarkPosition places a watermark on the print
+type WatermarkPosition string
+
+const (
+	PositionTopLeft     WatermarkPosition = "top-left"
+	PositionTop         WatermarkPosition = "top"
+	PositionTopRight    WatermarkPosition = "top-right"
+	PositionLeft        WatermarkPosition = "left"
+	PositionCenter      WatermarkPosition = "center"
+	PositionRight       WatermarkPosition = "right"
+	PositionBottomLeft  WatermarkPosition = "bottom-left"
+	PositionBottom      WatermarkPosition = "bottom"
+	PositionBottomRight WatermarkPosition = "bottom-right"
+)
+
+const (
+	// DefaultWatermarkMargin is the distance from the edges as a fraction of
+	// the shorter side of the print
+	DefaultWatermarkMargin = 0.02
+	// DefaultTextScale is the height of a text line as a fraction of the
+	// height of the print
+	DefaultTextScale = 0.04
+	// DefaultImageScale is the width of an image watermark as a fraction of
+	// the width of the print
+	DefaultImageScale = 0.2
+)
+
+// Watermark is a text or image stamped onto the print after it has been
+// fitted to the print size. Scale and Margin are relative to the print, so
+// that a watermark looks the same at any resolution. Zero values select the
+// defaults.
+type Watermark struct {
+	// Text is rendered with the embedded Go font; lines are separated by
+	// newlines
+	Text string `json:"text,omitempty"`
+	// ImagePath is a PNG (or any other supported image) to stamp. Exactly
+	// one of Text and ImagePath must be set.
+	ImagePath string `json:"imagePath,omitempty"`
+	// Position defaults to PositionBottomRight
+	Position WatermarkPosition `json:"position,omitempty"`
+	// Margin is the distance from the edges as a fraction (0-0.5) of the
+	// shorter side of the print
+	Margin float64 `json:"margin,omitempty"`
+	// Opacity is between 0 and 1; zero means fully opaque
+	Opacity float64 `json:"opacity,omitempty"`
+	// Scale is the line height of text as a fraction of the print height,
+	// or the width of an image as a fraction of the print width
+	Scale float64 `json:"scale,omitempty"`
+	// Color is the text color in #RRGGBB notation; white if empty
+	Color string `json:"color,omitempty"`
+}
+
+// ParseWatermarkPosition converts a position name, defaulting to
+// PositionBottomRight for an empty name
+func ParseWatermarkPosition(name string) (WatermarkPosition, error) {
+	switch p := WatermarkPosition(strings.ToLower(name)); p {
+	case "":
+		return PositionBottomRight, nil
+	case PositionTopLeft, PositionTop, PositionTopRight, PositionLeft, PositionCenter,
+		PositionRight, PositionBottomLeft, PositionBottom, PositionBottomRight:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown watermark position: %s", name)
+}
+
+// Validate checks the watermark options without loading the image
+func (w Watermark) Validate() error {
+	if (w.Text == "") == (w.ImagePath == "") {
+		return errors.New("watermark needs either text or an image")
+	}
+	if _, err := ParseWatermarkPosition(string(w.Position)); err != nil {
+		return err
+	}
+	if w.Margin < 0 || w.Margin > 0.5 {
+		return fmt.Errorf("invalid watermark margin: %g (must be between 0 and 0.5)", w.Margin)
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("invalid watermark opacity: %g (must be between 0 and 1)", w.Opacity)
+	}
+	if w.Scale < 0 || w.Scale > 1 {
+		return fmt.Errorf("invalid watermark scale: %g (must be between 0 and 1)", w.Scale)
+	}
+	if w.Color != "" {
+		if _, err := ParseHexColor(w.Color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filter validates the watermark and loads its image or font
+func (w Watermark) Filter() (Filter, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	f := &watermarkFilter{
+		position: PositionBottomRight,
+		margin:   DefaultWatermarkMargin,
+		opacity:  1,
+		color:    color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+	}
+	if w.Position != "" {
+		f.position, _ = ParseWatermarkPosition(string(w.Position))
+	}
+	if w.Margin > 0 {
+		f.margin = w.Margin
+	}
+	if w.Opacity > 0 {
+		f.opacity = w.Opacity
+	}
+	if w.Color != "" {
+		f.color, _ = ParseHexColor(w.Color)
+	}
+
+	if w.Text != "" {
+		f.text = strings.Split(w.Text, "\n")
+		f.scale = DefaultTextScale
+		if w.Scale > 0 {
+			f.scale = w.Scale
+		}
+		return f, nil
+	}
+
+	mark, err := loadOverlayImage(w.ImagePath, "watermark image")
+	if err != nil {
+		return nil, err
+	}
+	f.image = mark
+	f.scale = DefaultImageScale
+	if w.Scale > 0 {
+		f.scale = w.Scale
+	}
+	return f, nil
+}
+
+// loadOverlayImage decodes a watermark or frame image, which is small
+// enough to be subject to the default Limits. what names the image in
+// errors.
+func loadOverlayImage(path, what string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", what, err)
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	if err := (Limits{}).check(cfg); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	return img, nil
+}
+
+// watermarkFilter stamps a prepared text or image watermark
+type watermarkFilter struct {
+	text     []string
+	image    image.Image
+	position WatermarkPosition
+	margin   float64
+	opacity  float64
+	scale    float64
+	color    color.Color
+}
+
+func (f *watermarkFilter) Apply(img image.Image) image.Image {
+	dst := imaging.Clone(img)
+	bounds := dst.Bounds()
+
+	var mark image.Image
+	if f.image != nil {
+		width := max(1, int(math.Round(float64(bounds.Dx())*f.scale)))
+		mark = imaging.Resize(f.image, width, 0, imaging.Lanczos)
+	} else {
+		mark = renderText(f.text, max(1, float64(bounds.Dy())*f.scale), f.color)
+	}
+	if mark == nil {
+		return dst
+	}
+
+	margin := int(math.Round(float64(min(bounds.Dx(), bounds.Dy())) * f.margin))
+	r := placeWatermark(bounds, mark.Bounds().Size(), f.position, margin)
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(f.opacity * 255))})
+	draw.DrawMask(dst, r, mark, mark.Bounds().Min, mask, image.Point{}, draw.Over)
+	return dst
+}
+
+// placeWatermark returns where a watermark of the given size goes within
+// bounds
+func placeWatermark(bounds image.Rectangle, size image.Point, position WatermarkPosition, margin int) image.Rectangle {
+	x := bounds.Min.X + (bounds.Dx()-size.X)/2
+	y := bounds.Min.Y + (bounds.Dy()-size.Y)/2
+
+	switch position {
+	case PositionTopLeft, PositionLeft, PositionBottomLeft:
+		x = bounds.Min.X + margin
+	case PositionTopRight, PositionRight, PositionBottomRight:
+		x = bounds.Max.X - margin - size.X
+	}
+	switch position {
+	case PositionTopLeft, PositionTop, PositionTopRight:
+		y = bounds.Min.Y + margin
+	case PositionBottomLeft, PositionBottom, PositionBottomRight:
+		y = bounds.Max.Y - margin - size.Y
+	}
+
+	return image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x+size.X, y+size.Y)}
+}
+
+// renderText draws lines of text in the Go font onto a transparent image,
+// with lineHeight pixels per line
+func renderText(lines []string, lineHeight float64, c color.Color) image.Image {
+	f, err := goRegularFont.load()
+	if err != nil {
+		return nil
+	}
+	return drawText(f, lines, lineHeight, c, AlignLeft)
+}
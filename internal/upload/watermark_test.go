@@ -0,0 +1,152 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermark_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		watermark   Watermark
+		expectError string
+	}{
+		{name: "Text", watermark: Watermark{Text: "© yoshiken"}},
+		{name: "Image", watermark: Watermark{ImagePath: "logo.png", Position: PositionTopLeft, Margin: 0.05, Opacity: 0.5, Scale: 0.1}},
+		{name: "Colored text", watermark: Watermark{Text: "a", Color: "#ff8800"}},
+		{name: "Neither", watermark: Watermark{}, expectError: "either text or an image"},
+		{name: "Both", watermark: Watermark{Text: "a", ImagePath: "logo.png"}, expectError: "either text or an image"},
+		{name: "Unknown position", watermark: Watermark{Text: "a", Position: "middle"}, expectError: "unknown watermark position"},
+		{name: "Margin too large", watermark: Watermark{Text: "a", Margin: 0.6}, expectError: "invalid watermark margin"},
+		{name: "Opacity too large", watermark: Watermark{Text: "a", Opacity: 1.5}, expectError: "invalid watermark opacity"},
+		{name: "Negative scale", watermark: Watermark{Text: "a", Scale: -0.1}, expectError: "invalid watermark scale"},
+		{name: "Invalid color", watermark: Watermark{Text: "a", Color: "white"}, expectError: "invalid color"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.watermark.Validate()
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPlaceWatermark(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 80)
+	size := image.Pt(20, 10)
+
+	tests := []struct {
+		position WatermarkPosition
+		expected image.Point
+	}{
+		{PositionTopLeft, image.Pt(5, 5)},
+		{PositionTop, image.Pt(40, 5)},
+		{PositionTopRight, image.Pt(75, 5)},
+		{PositionLeft, image.Pt(5, 35)},
+		{PositionCenter, image.Pt(40, 35)},
+		{PositionRight, image.Pt(75, 35)},
+		{PositionBottomLeft, image.Pt(5, 65)},
+		{PositionBottom, image.Pt(40, 65)},
+		{PositionBottomRight, image.Pt(75, 65)},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.position), func(t *testing.T) {
+			r := placeWatermark(bounds, size, tt.position, 5)
+			assert.Equal(t, image.Rectangle{Min: tt.expected, Max: tt.expected.Add(size)}, r)
+		})
+	}
+}
+
+func TestWatermark_Image(t *testing.T) {
+	logoPath := filepath.Join(t.TempDir(), "logo.png")
+	logo := paintImage(image.NewNRGBA(image.Rect(0, 0, 10, 10)), color.NRGBA{R: 255, A: 255})
+	require.NoError(t, writePNG(logoPath, logo))
+
+	f, err := Watermark{ImagePath: logoPath, Position: PositionTopLeft, Opacity: 0.5, Scale: 0.25, Margin: 0.1}.Filter()
+	require.NoError(t, err)
+
+	img := paintImage(image.NewNRGBA(image.Rect(0, 0, 200, 100)), color.NRGBA{B: 255, A: 255})
+	result := f.Apply(img)
+	require.Equal(t, img.Bounds(), result.Bounds())
+
+	// The logo is scaled to 50x50 and placed 10 pixels from the corner at
+	// half opacity
+	stamped := color.NRGBAModel.Convert(result.At(35, 35)).(color.NRGBA)
+	assert.InDelta(t, 128, stamped.R, 2)
+	assert.InDelta(t, 128, stamped.B, 2)
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, color.NRGBAModel.Convert(result.At(5, 5)))
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, color.NRGBAModel.Convert(result.At(65, 35)))
+
+	// The source image is left alone
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, color.NRGBAModel.Convert(img.At(35, 35)))
+
+	_, err = Watermark{ImagePath: filepath.Join(t.TempDir(), "missing.png")}.Filter()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open watermark image")
+}
+
+func TestWatermark_Text(t *testing.T) {
+	f, err := Watermark{Text: "VRChat\nPrint", Position: PositionBottomRight, Scale: 0.1, Color: "#202020"}.Filter()
+	require.NoError(t, err)
+
+	result := f.Apply(createPatternImage(400, 300))
+	assertGolden(t, "watermark_text", result)
+}
+
+func TestPrepareImage_Watermarks(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "test.png")
+	require.NoError(t, createTestImage(imagePath, "png", 400, 300))
+
+	uploader := &Uploader{}
+
+	// Watermarks are stamped after the fit, so they don't change the size
+	data, _, err := uploader.prepareImage(context.Background(), Options{
+		ImagePath:  imagePath,
+		ResizeMode: ResizeFit,
+		Watermarks: []Watermark{{Text: "test"}},
+	})
+	require.NoError(t, err)
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, Print1080pWidth, cfg.Width)
+	assert.Equal(t, Print1080pHeight, cfg.Height)
+
+	_, _, err = uploader.prepareImage(context.Background(), Options{
+		ImagePath:  imagePath,
+		Watermarks: []Watermark{{Text: "ok"}, {Text: "bad", Opacity: 2}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "watermark 2: invalid watermark opacity")
+
+	_, _, err = uploader.prepareImage(context.Background(), Options{
+		ImagePath:  imagePath,
+		Watermarks: []Watermark{{ImagePath: filepath.Join(dir, "missing.png")}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "watermark 1: failed to open watermark image")
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
@@ -0,0 +1,269 @@
+// Package vrclog reads the worlds visited from VRChat's output logs, so that
+// screenshots without embedded metadata can be matched to a world by the
+// time they were taken
+package vrclog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when no visit covers a time
+var ErrNotFound = errors.New("no world visit found")
+
+// ErrNoDir is returned by an index without a log directory, e.g. when the
+// default one couldn't be determined
+var ErrNoDir = errors.New("no VRChat log directory configured")
+
+// LogPattern matches the log files VRChat writes, one per session
+const LogPattern = "output_log_*.txt"
+
+// timestampLayout starts every log line, in local time
+const timestampLayout = "2006.01.02 15:04:05"
+
+// maxLineSize is how much of a line is read; longer lines are truncated
+const maxLineSize = 64 * 1024
+
+// Log messages that mark world visits
+const (
+	joiningPrefix  = "[Behaviour] Joining "
+	roomPrefix     = "[Behaviour] Joining or Creating Room: "
+	enteringPrefix = "[Behaviour] Entering Room: "
+	leftMessage    = "[Behaviour] OnLeftRoom"
+)
+
+// Visit is a stay in a world instance
+type Visit struct {
+	WorldID   string `json:"worldId"`
+	WorldName string `json:"worldName,omitempty"`
+	// InstanceID is the part after the world ID, like 12345~region(jp)
+	InstanceID string    `json:"instanceId,omitempty"`
+	Joined     time.Time `json:"joined"`
+	// Left is when the world was left, or the last line of the log if the
+	// session ended in the world
+	Left time.Time `json:"left"`
+}
+
+// Contains reports whether t falls within the visit
+func (v Visit) Contains(t time.Time) bool {
+	return !t.Before(v.Joined) && !t.After(v.Left)
+}
+
+// DefaultDir returns where VRChat writes its logs: under LocalLow on
+// Windows, and in the Proton prefix of the Steam library elsewhere
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	localLow := filepath.Join("AppData", "LocalLow", "VRChat", "VRChat")
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, localLow)
+	}
+
+	// 438100 is VRChat's Steam app ID
+	prefix := filepath.Join("steamapps", "compatdata", "438100", "pfx", "drive_c", "users", "steamuser")
+	candidates := []string{
+		filepath.Join(home, ".steam", "steam", prefix, localLow),
+		filepath.Join(home, ".local", "share", "Steam", prefix, localLow),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam", prefix, localLow),
+	}
+	for _, dir := range candidates {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return candidates[0]
+}
+
+// Index is the timeline of world visits in a log directory. Log files are
+// parsed once and parsed again only when they change. A log that can't be
+// read is left out of the timeline and logged, since the visits only enrich
+// uploads.
+type Index struct {
+	dir    string
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	files map[string]logFile
+}
+
+// logFile caches the visits of a log file
+type logFile struct {
+	size    int64
+	modTime time.Time
+	visits  []Visit
+}
+
+// NewIndex returns an index of the logs in dir, which doesn't have to exist.
+// Logs that can't be read are reported to logger, if set.
+func NewIndex(dir string, logger *slog.Logger) *Index {
+	return &Index{dir: dir, logger: logger, files: map[string]logFile{}}
+}
+
+// Visits returns the visits in all logs, ordered by the time they were
+// joined
+func (x *Index) Visits() ([]Visit, error) {
+	// An empty directory would search the working directory
+	if x.dir == "" {
+		return nil, ErrNoDir
+	}
+
+	paths, err := filepath.Glob(filepath.Join(x.dir, LogPattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	files := make(map[string]logFile, len(paths))
+	var visits []Visit
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		cached, ok := x.files[path]
+		if !ok || cached.size != info.Size() || !cached.modTime.Equal(info.ModTime()) {
+			parsed, err := parseFile(path)
+			if err != nil {
+				// Remember the broken log too, so that it is reported once
+				// per change rather than on every lookup
+				if x.logger != nil {
+					x.logger.Warn("skipping unreadable VRChat log", "file", filepath.Base(path), "error", err)
+				}
+				parsed = nil
+			}
+			cached = logFile{size: info.Size(), modTime: info.ModTime(), visits: parsed}
+		}
+		files[path] = cached
+		visits = append(visits, cached.visits...)
+	}
+	x.files = files
+
+	sort.SliceStable(visits, func(i, j int) bool {
+		return visits[i].Joined.Before(visits[j].Joined)
+	})
+	return visits, nil
+}
+
+// Lookup returns the visit that t falls within. If visits overlap, which
+// happens when VRChat ran twice, the one joined last wins.
+func (x *Index) Lookup(t time.Time) (*Visit, error) {
+	visits, err := x.Visits()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(visits) - 1; i >= 0; i-- {
+		if visits[i].Contains(t) {
+			return &visits[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w at %s", ErrNotFound, t.Format(time.RFC3339))
+}
+
+func parseFile(path string) ([]Visit, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	visits, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log %s: %w", filepath.Base(path), err)
+	}
+	return visits, nil
+}
+
+// Parse reads the world visits from a VRChat log. Timestamps are taken to be
+// in local time, as VRChat writes them.
+func Parse(r io.Reader) ([]Visit, error) {
+	reader := bufio.NewReaderSize(r, maxLineSize)
+
+	var visits []Visit
+	var current *Visit
+	var last time.Time
+	closeVisit := func(at time.Time) {
+		if current != nil {
+			current.Left = at
+			visits = append(visits, *current)
+			current = nil
+		}
+	}
+
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) < len(timestampLayout) {
+			continue
+		}
+		t, err := time.ParseInLocation(timestampLayout, line[:len(timestampLayout)], time.Local)
+		if err != nil {
+			// Continuation lines of multi-line messages have no timestamp
+			continue
+		}
+		last = t
+
+		_, message, ok := strings.Cut(line[len(timestampLayout):], " -  ")
+		if !ok {
+			continue
+		}
+		message = strings.TrimSpace(message)
+
+		switch {
+		case strings.HasPrefix(message, roomPrefix):
+			if current != nil && current.WorldName == "" {
+				current.WorldName = strings.TrimSpace(strings.TrimPrefix(message, roomPrefix))
+			}
+		case strings.HasPrefix(message, enteringPrefix):
+			if current != nil && current.WorldName == "" {
+				current.WorldName = strings.TrimSpace(strings.TrimPrefix(message, enteringPrefix))
+			}
+		case strings.HasPrefix(message, joiningPrefix+"wrld_"):
+			closeVisit(t)
+			location := strings.TrimPrefix(message, joiningPrefix)
+			worldID, instanceID, _ := strings.Cut(location, ":")
+			current = &Visit{WorldID: worldID, InstanceID: instanceID, Joined: t}
+		case message == leftMessage:
+			closeVisit(t)
+		}
+	}
+	closeVisit(last)
+	return visits, nil
+}
+
+// readLine returns the next line without its line ending, truncated to the
+// reader's buffer size
+func readLine(r *bufio.Reader) (string, error) {
+	line, isPrefix, err := r.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	s := string(line)
+	for isPrefix {
+		if _, isPrefix, err = r.ReadLine(); err != nil {
+			break
+		}
+	}
+	return strings.TrimRight(s, "\r"), nil
+}
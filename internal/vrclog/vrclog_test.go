@@ -0,0 +1,176 @@
+package vrclog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sessionLog = `2024.03.15 21:00:00 Log        -  VRC Analytics Initialized
+2024.03.15 21:00:10 Log        -  [Behaviour] Joining wrld_4432ea9b-729c-46e3-8eaf-846aa0a37fdd:12345~region(jp)
+2024.03.15 21:00:10 Log        -  [Behaviour] Joining or Creating Room: VRChat Home
+
+2024.03.15 21:00:15 Log        -  [Behaviour] Entering Room: VRChat Home
+2024.03.15 21:10:00 Error      -  Something failed
+  at SomeStackFrame ()
+2024.03.15 21:20:00 Log        -  [Behaviour] OnLeftRoom
+2024.03.15 21:20:05 Log        -  [Behaviour] Joining wrld_12345678-1234-1234-1234-123456789012:67890~friends(usr_abc)~region(us)
+2024.03.15 21:20:05 Log        -  [Behaviour] Joining or Creating Room: 日本の街
+2024.03.15 21:45:30 Log        -  [Behaviour] Joining wrld_aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee
+2024.03.15 21:50:00 Log        -  [VRC Camera] Took screenshot to: C:\Users\user\Pictures\VRChat\VRChat_2024-03-15_21-50-00.000_1920x1080.png
+`
+
+func localTime(hour, min, sec int) time.Time {
+	return time.Date(2024, 3, 15, hour, min, sec, 0, time.Local)
+}
+
+func TestParse(t *testing.T) {
+	visits, err := Parse(strings.NewReader(strings.ReplaceAll(sessionLog, "\n", "\r\n")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Visit{
+		{
+			WorldID:    "wrld_4432ea9b-729c-46e3-8eaf-846aa0a37fdd",
+			WorldName:  "VRChat Home",
+			InstanceID: "12345~region(jp)",
+			Joined:     localTime(21, 0, 10),
+			Left:       localTime(21, 20, 0),
+		},
+		{
+			WorldID:    "wrld_12345678-1234-1234-1234-123456789012",
+			WorldName:  "日本の街",
+			InstanceID: "67890~friends(usr_abc)~region(us)",
+			Joined:     localTime(21, 20, 5),
+			Left:       localTime(21, 45, 30),
+		},
+		{
+			// The session ended in this world
+			WorldID: "wrld_aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+			Joined:  localTime(21, 45, 30),
+			Left:    localTime(21, 50, 0),
+		},
+	}, visits)
+}
+
+func TestParse_LongLines(t *testing.T) {
+	log := "2024.03.15 21:00:00 Log        -  " + strings.Repeat("x", 3*maxLineSize) + "\n" +
+		"2024.03.15 21:00:10 Log        -  [Behaviour] Joining wrld_long:1\n" +
+		"2024.03.15 21:00:20 Log        -  [Behaviour] OnLeftRoom\n"
+
+	visits, err := Parse(strings.NewReader(log))
+	require.NoError(t, err)
+	require.Len(t, visits, 1)
+	assert.Equal(t, "wrld_long", visits[0].WorldID)
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output_log_2024-03-15_21-00-00.txt"), []byte(sessionLog), 0600))
+	// Files that aren't logs are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Player.log"), []byte(sessionLog), 0600))
+
+	index := NewIndex(dir, nil)
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected string
+	}{
+		{"First world", localTime(21, 5, 0), "wrld_4432ea9b-729c-46e3-8eaf-846aa0a37fdd"},
+		{"Joined", localTime(21, 20, 5), "wrld_12345678-1234-1234-1234-123456789012"},
+		{"Switched worlds", localTime(21, 45, 30), "wrld_aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"},
+		{"Last line", localTime(21, 50, 0), "wrld_aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"},
+		{"Between worlds", localTime(21, 20, 2), ""},
+		{"Before the session", localTime(20, 0, 0), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visit, err := index.Lookup(tt.time)
+			if tt.expected == "" {
+				assert.True(t, errors.Is(err, ErrNotFound))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, visit.WorldID)
+		})
+	}
+}
+
+func TestIndex_Reload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "output_log_2024-03-15_21-00-00.txt")
+	require.NoError(t, os.WriteFile(path, []byte(sessionLog), 0600))
+
+	index := NewIndex(dir, nil)
+	visits, err := index.Visits()
+	require.NoError(t, err)
+	assert.Len(t, visits, 3)
+
+	// VRChat keeps appending to the log of a running session
+	appended := sessionLog + "2024.03.15 22:00:00 Log        -  [Behaviour] Joining wrld_next:1\n" +
+		"2024.03.15 22:00:01 Log        -  [Behaviour] Entering Room: Next World\n"
+	require.NoError(t, os.WriteFile(path, []byte(appended), 0600))
+
+	visit, err := index.Lookup(localTime(22, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, Visit{WorldID: "wrld_next", WorldName: "Next World", InstanceID: "1", Joined: localTime(22, 0, 0), Left: localTime(22, 0, 1)}, *visit)
+
+	// A second session is merged into the timeline
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output_log_2024-03-16_10-00-00.txt"),
+		[]byte("2024.03.16 10:00:00 Log        -  [Behaviour] Joining wrld_morning:1\n2024.03.16 11:00:00 Log        -  Quit\n"), 0600))
+	visits, err = index.Visits()
+	require.NoError(t, err)
+	require.Len(t, visits, 5)
+	assert.Equal(t, "wrld_morning", visits[4].WorldID)
+
+	// Deleted logs drop out
+	require.NoError(t, os.Remove(path))
+	visits, err = index.Visits()
+	require.NoError(t, err)
+	assert.Len(t, visits, 1)
+}
+
+func TestIndex_SkipsUnreadableLogs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output_log_2024-03-15_21-00-00.txt"), []byte(sessionLog), 0600))
+	// A log that can't be read, here because it is a directory
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "output_log_2024-03-16_10-00-00.txt"), 0700))
+
+	var buf bytes.Buffer
+	index := NewIndex(dir, slog.New(slog.NewTextHandler(&buf, nil)))
+	visits, err := index.Visits()
+	require.NoError(t, err)
+	assert.Len(t, visits, 3)
+	assert.Contains(t, buf.String(), "output_log_2024-03-16_10-00-00.txt")
+
+	// It is reported once, not on every lookup
+	buf.Reset()
+	visit, err := index.Lookup(localTime(21, 5, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "wrld_4432ea9b-729c-46e3-8eaf-846aa0a37fdd", visit.WorldID)
+	assert.Empty(t, buf.String())
+}
+
+func TestIndex_MissingDir(t *testing.T) {
+	index := NewIndex(filepath.Join(t.TempDir(), "missing"), nil)
+	visits, err := index.Visits()
+	require.NoError(t, err)
+	assert.Empty(t, visits)
+
+	_, err = index.Lookup(time.Now())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestIndex_NoDir(t *testing.T) {
+	// The working directory isn't searched
+	_, err := NewIndex("", nil).Lookup(localTime(21, 5, 0))
+	assert.ErrorIs(t, err, ErrNoDir)
+}
@@ -2,22 +2,74 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"image/color"
+	"io"
+	"log/slog"
+	"math"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"github.com/yoshiken/vrc-print-upload/internal/auth"
+	"github.com/yoshiken/vrc-print-upload/internal/client"
 	"github.com/yoshiken/vrc-print-upload/internal/config"
+	"github.com/yoshiken/vrc-print-upload/internal/frame"
+	"github.com/yoshiken/vrc-print-upload/internal/logging"
+	"github.com/yoshiken/vrc-print-upload/internal/preset"
+	"github.com/yoshiken/vrc-print-upload/internal/prints"
 	"github.com/yoshiken/vrc-print-upload/internal/upload"
+	"github.com/yoshiken/vrc-print-upload/internal/vrclog"
 )
 
+// cropPreviewSize is the longest side of crop preview thumbnails
+const cropPreviewSize = 640
+
+// appVersion is written into the metadata of uploaded prints. Keep it in
+// sync with productVersion in wails.json.
+const appVersion = "1.0.0"
+
+// captureTimeLayout is the capture time exchanged with the frontend, in
+// local time as used by datetime-local inputs
+const captureTimeLayout = "2006-01-02T15:04:05"
+
 // App struct
 type App struct {
 	ctx           context.Context
+	cancel        context.CancelFunc
 	config        *config.Config
+	logger        *slog.Logger
+	logCloser     io.Closer
 	authClient    *auth.Client
+	apiClient     *client.Client
 	uploadService *upload.Uploader
+	printService  *prints.Service
+	presets       *preset.Store
+	frames        *frame.Library
+	logs          *vrclog.Index
+	previews      *previewCache
+	// userMu guards displayName and userID, which bindings running
+	// concurrently read and write
+	userMu sync.Mutex
+	// displayName is the logged in user, recorded as the print author
+	displayName string
+	// userID is the logged in user whose prints the gallery lists
+	userID string
+
+	galleryMu sync.Mutex
+	// gallery holds the prints listed so far by ID
+	gallery map[string]prints.Print
+
+	uploadMu     sync.Mutex
+	cancelUpload context.CancelFunc
+
+	backupMu     sync.Mutex
+	cancelBackup context.CancelFunc
 }
 
 // LoginRequest represents login request data
@@ -47,7 +99,43 @@ type UploadRequest struct {
 	Note      string `json:"note"`
 	WorldID   string `json:"worldId"`
 	WorldName string `json:"worldName"`
-	NoResize  bool   `json:"noResize"`
+	// CaptureTime is when the image was taken in local time, formatted as
+	// 2006-01-02T15:04:05; empty uses the modification time of the file
+	CaptureTime string `json:"captureTime,omitempty"`
+	// Timestamp is the date of the print, in RFC 3339 or formatted as
+	// 2006-01-02T15:04:05 in TimeZone; empty uses the configured source
+	Timestamp string `json:"timestamp,omitempty"`
+	// TimeZone is an IANA zone name like Asia/Tokyo; empty uses the
+	// configured zone, or local time
+	TimeZone string `json:"timeZone,omitempty"`
+	// ResizeMode is one of "stretch", "fit", "fill" or "original"
+	ResizeMode string `json:"resizeMode"`
+	// PadColor is the letterbox color for "fit" in #RRGGBB notation
+	PadColor string `json:"padColor,omitempty"`
+	// CropAnchor is one of "center", "focal" or "smart" and applies to "fill"
+	CropAnchor string `json:"cropAnchor,omitempty"`
+	// FocalX and FocalY are the normalized crop center for "focal"
+	FocalX float64 `json:"focalX"`
+	FocalY float64 `json:"focalY"`
+	// Rotate is a clockwise rotation of 0, 90, 180 or 270 degrees, applied
+	// before the flips
+	Rotate         int  `json:"rotate"`
+	FlipHorizontal bool `json:"flipHorizontal"`
+	FlipVertical   bool `json:"flipVertical"`
+	// Shrink always uses the smallest PNG encoding; Quantize allows a
+	// dithered 256-color palette when the PNG is too large or shrinking
+	Shrink   bool `json:"shrink"`
+	Quantize bool `json:"quantize"`
+	// PreserveAlpha keeps transparency; otherwise it is flattened onto
+	// BackgroundColor in #RRGGBB notation, white if empty
+	PreserveAlpha   bool   `json:"preserveAlpha"`
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	// Filters are processing steps applied in order before resizing
+	Filters []upload.FilterSpec `json:"filters"`
+	// Frame is the name of a frame template; no frame if empty
+	Frame string `json:"frame,omitempty"`
+	// Watermarks are stamped in order after the frame
+	Watermarks []upload.Watermark `json:"watermarks"`
 }
 
 // UploadResponse represents upload response data
@@ -56,6 +144,103 @@ type UploadResponse struct {
 	Message string `json:"message"`
 	FileID  string `json:"fileId,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Strategy is how the PNG was made to fit: "default",
+	// "best-compression", "quantize" or "downscale"
+	Strategy string `json:"strategy,omitempty"`
+	// Width, Height and Size describe the uploaded PNG
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	Size   int `json:"size,omitempty"`
+	// ExportPath and ManifestPath are the files written by ExportImage
+	ExportPath   string `json:"exportPath,omitempty"`
+	ManifestPath string `json:"manifestPath,omitempty"`
+}
+
+// CropPreviewResponse shows where the image will be cropped in "fill" mode.
+// All crop coordinates are normalized to the image size.
+type CropPreviewResponse struct {
+	Success    bool    `json:"success"`
+	Error      string  `json:"error,omitempty"`
+	Thumbnail  string  `json:"thumbnail,omitempty"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	CropX      float64 `json:"cropX"`
+	CropY      float64 `json:"cropY"`
+	CropWidth  float64 `json:"cropWidth"`
+	CropHeight float64 `json:"cropHeight"`
+	FocalX     float64 `json:"focalX"`
+	FocalY     float64 `json:"focalY"`
+}
+
+// ImageValidationResponse describes a selected image file. Width and Height
+// are the displayed dimensions; OutputWidth and OutputHeight the dimensions
+// of the uploaded PNG.
+type ImageValidationResponse struct {
+	Valid        bool   `json:"valid"`
+	Error        string `json:"error,omitempty"`
+	Format       string `json:"format,omitempty"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"fileSize"`
+	ColorModel   string `json:"colorModel,omitempty"`
+	Frames       int    `json:"frames"`
+	OutputWidth  int    `json:"outputWidth"`
+	OutputHeight int    `json:"outputHeight"`
+}
+
+// UploadProgressEvent is emitted as "upload:progress" while an upload runs
+type UploadProgressEvent struct {
+	Phase     string  `json:"phase"`
+	BytesSent int64   `json:"bytesSent"`
+	Total     int64   `json:"total"`
+	Rate      float64 `json:"rate"`
+}
+
+// RateLimitStatusResponse represents the current API rate-limit state
+type RateLimitStatusResponse struct {
+	Limited           bool   `json:"limited"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds"`
+	Limit             int    `json:"limit"`
+	Remaining         int    `json:"remaining"`
+	ResetAt           string `json:"resetAt,omitempty"`
+}
+
+// PresetsResponse carries the saved presets after a preset operation
+type PresetsResponse struct {
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
+	Presets []preset.Preset `json:"presets"`
+}
+
+// FramesResponse lists the frame templates
+type FramesResponse struct {
+	Success bool                   `json:"success"`
+	Error   string                 `json:"error,omitempty"`
+	Frames  []upload.FrameTemplate `json:"frames"`
+}
+
+// ImageMetadataResponse is what is known about an image before it is
+// uploaded, for pre-filling the upload form
+type ImageMetadataResponse struct {
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	WorldID   string `json:"worldId,omitempty"`
+	WorldName string `json:"worldName,omitempty"`
+	Author    string `json:"author,omitempty"`
+	// CaptureTime is in local time, formatted as 2006-01-02T15:04:05;
+	// empty if unknown
+	CaptureTime string `json:"captureTime,omitempty"`
+	// WorldFromLog is set when the world isn't embedded in the image but
+	// was found in the VRChat logs by the capture time
+	WorldFromLog bool `json:"worldFromLog"`
+}
+
+// CircuitBreakerStatusResponse represents whether the API is considered available
+type CircuitBreakerStatusResponse struct {
+	State               string `json:"state"`
+	Available           bool   `json:"available"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	RetryAfterSeconds   int    `json:"retryAfterSeconds"`
 }
 
 // NewApp creates a new App application struct
@@ -66,26 +251,64 @@ func NewApp() *App {
 		panic(fmt.Sprintf("Failed to load config: %v", err))
 	}
 
+	// Set up opt-in API logging; a broken logging setup shouldn't stop the
+	// app, so fall back to logging to stderr
+	logger, logCloser, err := logging.New(cfg)
+	if err != nil {
+		logger, logCloser = slog.New(slog.NewTextHandler(os.Stderr, nil)), nil
+		logger.Error("failed to set up logging", "error", err)
+	}
+
+	logDir := cfg.VRChatLogDir
+	if logDir == "" {
+		logDir = vrclog.DefaultDir()
+	}
+
 	// Initialize auth client
 	authClient := auth.NewClient(cfg)
+	logging.Instrument(authClient.GetHTTPClient(), logger)
 
 	return &App{
 		config:     cfg,
+		logger:     logger,
+		logCloser:  logCloser,
 		authClient: authClient,
+		presets:    preset.NewStore(cfg.PresetFile()),
+		frames:     frame.NewLibrary(cfg.FrameDir(), logger),
+		logs:       vrclog.NewIndex(logDir, logger),
+		previews:   newPreviewCache(),
 	}
 }
 
 // startup is called when the app starts. The context is saved
-// so we can call the runtime methods
+// so we can call the runtime methods and cancel requests on shutdown
 func (a *App) startup(ctx context.Context) {
-	a.ctx = ctx
+	a.ctx, a.cancel = context.WithCancel(ctx)
 	
 	// Initialize upload service if user is already authenticated
 	if a.IsAuthenticated() {
-		a.uploadService = upload.New(a.authClient.GetHTTPClient())
+		a.initServices()
+	}
+}
+
+// shutdown is called when the app is closing
+func (a *App) shutdown(ctx context.Context) {
+	if a.cancel != nil {
+		a.cancel()
+	}
+	if a.logCloser != nil {
+		a.logCloser.Close()
 	}
 }
 
+// initServices creates the shared API client and the services using it
+func (a *App) initServices() {
+	a.apiClient = client.New(a.authClient.GetHTTPClient())
+	logging.Instrument(a.apiClient.Client, a.logger)
+	a.uploadService = upload.New(a.apiClient.Client)
+	a.printService = prints.New(a.apiClient.Client)
+}
+
 // IsAuthenticated checks if user is logged in
 func (a *App) IsAuthenticated() bool {
 	// First check if we have valid cookies
@@ -94,7 +317,7 @@ func (a *App) IsAuthenticated() bool {
 	}
 	
 	// Also verify with the API to make sure the session is still valid
-	_, err := a.authClient.GetCurrentUser()
+	_, err := a.authClient.GetCurrentUser(a.ctx)
 	return err == nil
 }
 
@@ -105,7 +328,7 @@ func (a *App) Login(req LoginRequest) LoginResponse {
 		Password: req.Password,
 	}
 
-	err := a.authClient.Login(opts)
+	err := a.authClient.Login(a.ctx, opts)
 	if err != nil {
 		// Check if it's a 2FA error
 		errMsg := err.Error()
@@ -124,14 +347,17 @@ func (a *App) Login(req LoginRequest) LoginResponse {
 	}
 
 	// Get user info after successful login
-	user, err := a.authClient.GetCurrentUser()
+	user, err := a.authClient.GetCurrentUser(a.ctx)
 	displayName := ""
+	userID := ""
 	if err == nil && user != nil {
 		displayName = user.DisplayName
+		userID = user.ID
 	}
+	a.setUser(userID, displayName)
 
 	// Initialize upload service after successful login
-	a.uploadService = upload.New(a.authClient.GetHTTPClient())
+	a.initServices()
 
 	return LoginResponse{
 		Success:         true,
@@ -145,9 +371,9 @@ func (a *App) VerifyTwoFactor(req TwoFactorRequest) LoginResponse {
 	var err error
 	
 	if req.IsRecoveryCode {
-		err = a.authClient.VerifyRecoveryCode(req.Code)
+		err = a.authClient.VerifyRecoveryCode(a.ctx, req.Code)
 	} else {
-		err = a.authClient.VerifyTOTPCode(req.Code)
+		err = a.authClient.VerifyTOTPCode(a.ctx, req.Code)
 	}
 	
 	if err != nil {
@@ -158,14 +384,17 @@ func (a *App) VerifyTwoFactor(req TwoFactorRequest) LoginResponse {
 	}
 	
 	// Get user info after successful 2FA verification
-	user, err := a.authClient.GetCurrentUser()
+	user, err := a.authClient.GetCurrentUser(a.ctx)
 	displayName := ""
+	userID := ""
 	if err == nil && user != nil {
 		displayName = user.DisplayName
+		userID = user.ID
 	}
+	a.setUser(userID, displayName)
 
 	// Initialize upload service after successful 2FA
-	a.uploadService = upload.New(a.authClient.GetHTTPClient())
+	a.initServices()
 
 	return LoginResponse{
 		Success:         true,
@@ -184,7 +413,11 @@ func (a *App) Logout() LoginResponse {
 		}
 	}
 
+	a.apiClient = nil
 	a.uploadService = nil
+	a.printService = nil
+	a.setUser("", "")
+	a.resetGallery()
 	return LoginResponse{
 		Success: true,
 		Message: "Logged out successfully",
@@ -193,13 +426,14 @@ func (a *App) Logout() LoginResponse {
 
 // GetCurrentUser returns current user info
 func (a *App) GetCurrentUser() LoginResponse {
-	user, err := a.authClient.GetCurrentUser()
+	user, err := a.authClient.GetCurrentUser(a.ctx)
 	if err != nil {
 		return LoginResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to get user info: %v", err),
 		}
 	}
+	a.setUser(user.ID, user.DisplayName)
 
 	return LoginResponse{
 		Success:         true,
@@ -207,6 +441,22 @@ func (a *App) GetCurrentUser() LoginResponse {
 	}
 }
 
+// setUser records the logged in user
+func (a *App) setUser(userID, displayName string) {
+	a.userMu.Lock()
+	defer a.userMu.Unlock()
+	a.userID = userID
+	a.displayName = displayName
+}
+
+// currentDisplayName returns the display name of the logged in user, if
+// known
+func (a *App) currentDisplayName() string {
+	a.userMu.Lock()
+	defer a.userMu.Unlock()
+	return a.displayName
+}
+
 // UploadImage uploads an image to VRChat
 func (a *App) UploadImage(req UploadRequest) UploadResponse {
 	if a.uploadService == nil {
@@ -216,44 +466,471 @@ func (a *App) UploadImage(req UploadRequest) UploadResponse {
 		}
 	}
 
-	// Validate file path
-	if req.ImagePath == "" {
+	opts, err := a.uploadOptions(req)
+	if err != nil {
 		return UploadResponse{
 			Success: false,
-			Error:   "No image selected",
+			Error:   err.Error(),
 		}
 	}
+	opts.Progress = a.emitProgress
 
-	// Convert to absolute path if needed
-	absPath, err := filepath.Abs(req.ImagePath)
-	if err != nil {
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+	if !a.setUploadCancel(cancel) {
 		return UploadResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Invalid file path: %v", err),
+			Error:   "An upload or export is already running",
 		}
 	}
+	defer a.setUploadCancel(nil)
 
-	opts := upload.Options{
-		ImagePath: absPath,
-		Note:      req.Note,
-		WorldID:   req.WorldID,
-		WorldName: req.WorldName,
-		NoResize:  req.NoResize,
-	}
-
-	result, err := a.uploadService.Upload(opts)
+	result, err := a.uploadService.Upload(ctx, opts)
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return UploadResponse{
+				Success: false,
+				Error:   "Upload cancelled",
+			}
+		}
+
+		var unavailableErr *client.APIUnavailableError
+		if errors.As(err, &unavailableErr) {
+			return UploadResponse{
+				Success: false,
+				Error:   fmt.Sprintf("VRChat API is unavailable. Try again in %ds", int(math.Ceil(unavailableErr.RetryAfter.Seconds()))),
+			}
+		}
+
+		var rateLimitErr *client.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			return UploadResponse{
+				Success: false,
+				Error:   fmt.Sprintf("Rate limited. Next upload allowed in %ds", int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))),
+			}
+		}
+
 		return UploadResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Upload failed: %v", err),
 		}
 	}
 
-	return UploadResponse{
+	response := UploadResponse{
 		Success: true,
 		Message: "Upload successful",
 		FileID:  result.FileID,
 	}
+	if result.Encoding != nil {
+		response.Strategy = string(result.Encoding.Strategy)
+		response.Width = result.Encoding.Width
+		response.Height = result.Encoding.Height
+		response.Size = result.Encoding.Size
+	}
+	return response
+}
+
+// ExportImage is a dry run of UploadImage: it prepares the image exactly as
+// for an upload and writes the PNG and a JSON manifest of the form fields to
+// dir instead of sending them
+func (a *App) ExportImage(req UploadRequest, dir string) UploadResponse {
+	if dir == "" {
+		return UploadResponse{
+			Success: false,
+			Error:   "No export directory selected",
+		}
+	}
+
+	opts, err := a.uploadOptions(req)
+	if err != nil {
+		return UploadResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+	opts.ExportDir = dir
+	opts.Progress = a.emitProgress
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+	if !a.setUploadCancel(cancel) {
+		return UploadResponse{
+			Success: false,
+			Error:   "An upload or export is already running",
+		}
+	}
+	defer a.setUploadCancel(nil)
+
+	result, err := upload.Export(ctx, opts)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return UploadResponse{
+				Success: false,
+				Error:   "Export cancelled",
+			}
+		}
+		return UploadResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Export failed: %v", err),
+		}
+	}
+
+	response := UploadResponse{
+		Success:      true,
+		Message:      "Export successful",
+		ExportPath:   result.ExportPath,
+		ManifestPath: result.ManifestPath,
+	}
+	if result.Encoding != nil {
+		response.Strategy = string(result.Encoding.Strategy)
+		response.Width = result.Encoding.Width
+		response.Height = result.Encoding.Height
+		response.Size = result.Encoding.Size
+	}
+	return response
+}
+
+// emitProgress forwards upload progress to the frontend
+func (a *App) emitProgress(p upload.Progress) {
+	runtime.EventsEmit(a.ctx, "upload:progress", UploadProgressEvent{
+		Phase:     string(p.Phase),
+		BytesSent: p.BytesSent,
+		Total:     p.Total,
+		Rate:      p.Rate,
+	})
+}
+
+// PreviewCrop shows which part of the selected image is kept when it is
+// fill-cropped with the requested crop anchor
+func (a *App) PreviewCrop(req UploadRequest) CropPreviewResponse {
+	opts, err := a.uploadOptions(req)
+	if err != nil {
+		return CropPreviewResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	preview, err := upload.PreviewCrop(a.ctx, opts, cropPreviewSize)
+	if err != nil {
+		return CropPreviewResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to preview crop: %v", err),
+		}
+	}
+
+	width, height := float64(preview.Width), float64(preview.Height)
+	return CropPreviewResponse{
+		Success:    true,
+		Thumbnail:  "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(preview.Thumbnail),
+		Width:      preview.Width,
+		Height:     preview.Height,
+		CropX:      float64(preview.Crop.Min.X) / width,
+		CropY:      float64(preview.Crop.Min.Y) / height,
+		CropWidth:  float64(preview.Crop.Dx()) / width,
+		CropHeight: float64(preview.Crop.Dy()) / height,
+		FocalX:     preview.FocalPoint.X,
+		FocalY:     preview.FocalPoint.Y,
+	}
+}
+
+// uploadOptions validates the request and converts it to upload options
+func (a *App) uploadOptions(req UploadRequest) (upload.Options, error) {
+	// Validate file path
+	if req.ImagePath == "" {
+		return upload.Options{}, errors.New("No image selected")
+	}
+
+	// Convert to absolute path if needed
+	absPath, err := filepath.Abs(req.ImagePath)
+	if err != nil {
+		return upload.Options{}, fmt.Errorf("Invalid file path: %v", err)
+	}
+
+	var captureTime time.Time
+	if req.CaptureTime != "" {
+		captureTime, err = upload.ParseTimestamp(req.CaptureTime, nil)
+		if err != nil {
+			return upload.Options{}, fmt.Errorf("Invalid capture time: %s", req.CaptureTime)
+		}
+	}
+
+	zoneName := req.TimeZone
+	if zoneName == "" {
+		zoneName = a.config.TimeZone
+	}
+	var timeZone *time.Location
+	if zoneName != "" {
+		timeZone, err = time.LoadLocation(zoneName)
+		if err != nil {
+			return upload.Options{}, fmt.Errorf("Unknown time zone: %s", zoneName)
+		}
+	}
+
+	var timestamp time.Time
+	if req.Timestamp != "" {
+		timestamp, err = upload.ParseTimestamp(req.Timestamp, timeZone)
+		if err != nil {
+			return upload.Options{}, err
+		}
+	}
+
+	timestampSource, err := upload.ParseTimestampSource(a.config.TimestampSource)
+	if err != nil {
+		return upload.Options{}, err
+	}
+
+	resizeMode, err := upload.ParseResizeMode(req.ResizeMode)
+	if err != nil {
+		return upload.Options{}, err
+	}
+
+	var padColor color.Color
+	if req.PadColor != "" {
+		c, err := upload.ParseHexColor(req.PadColor)
+		if err != nil {
+			return upload.Options{}, err
+		}
+		padColor = c
+	}
+
+	var background color.Color
+	if req.BackgroundColor != "" {
+		c, err := upload.ParseHexColor(req.BackgroundColor)
+		if err != nil {
+			return upload.Options{}, err
+		}
+		background = c
+	}
+
+	cropAnchor, err := upload.ParseCropAnchor(req.CropAnchor)
+	if err != nil {
+		return upload.Options{}, err
+	}
+
+	focalPoint := upload.FocalPoint{X: req.FocalX, Y: req.FocalY}
+	if cropAnchor == upload.CropFocal && !focalPoint.Valid() {
+		return upload.Options{}, fmt.Errorf("Invalid focal point: %.2f, %.2f", req.FocalX, req.FocalY)
+	}
+
+	filters, err := upload.ParseFilters(req.Filters)
+	if err != nil {
+		return upload.Options{}, err
+	}
+
+	var frameTemplate *upload.FrameTemplate
+	if req.Frame != "" {
+		frameTemplate, err = a.frames.Get(req.Frame)
+		if err != nil {
+			return upload.Options{}, err
+		}
+	}
+
+	omit, err := upload.ParseMetadataFields(a.config.MetadataOmit)
+	if err != nil {
+		return upload.Options{}, err
+	}
+
+	return upload.Options{
+		ImagePath:       absPath,
+		Note:            req.Note,
+		WorldID:         req.WorldID,
+		WorldName:       req.WorldName,
+		CaptureTime:     captureTime,
+		Timestamp:       timestamp,
+		TimestampSource: timestampSource,
+		TimeZone:        timeZone,
+		ResizeMode:      resizeMode,
+		PadColor:        padColor,
+		CropAnchor:      cropAnchor,
+		FocalPoint:      focalPoint,
+		Rotate:          upload.Rotation(req.Rotate),
+		FlipHorizontal:  req.FlipHorizontal,
+		FlipVertical:    req.FlipVertical,
+		Filters:         filters,
+		PreserveAlpha:   req.PreserveAlpha,
+		Background:      background,
+		Frame:           frameTemplate,
+		Watermarks:      req.Watermarks,
+		Limits: upload.Limits{
+			MaxPixels: a.config.MaxImagePixels,
+			MaxMemory: a.config.MaxDecodeMemoryMB * 1024 * 1024,
+		},
+		Output: upload.OutputOptions{
+			Shrink:   req.Shrink,
+			Quantize: req.Quantize,
+		},
+		Metadata: upload.MetadataOptions{
+			Disabled: !a.config.EmbedMetadata,
+			Author:   a.currentDisplayName(),
+			Software: "vrc-print-upload/" + appVersion,
+			Omit:     omit,
+		},
+	}, nil
+}
+
+// GetPresets returns the saved processing presets
+func (a *App) GetPresets() PresetsResponse {
+	return a.presetsResponse()
+}
+
+// SavePreset saves a processing preset, replacing one with the same name
+func (a *App) SavePreset(p preset.Preset) PresetsResponse {
+	if err := a.presets.Save(p); err != nil {
+		return PresetsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to save preset: %v", err),
+		}
+	}
+	return a.presetsResponse()
+}
+
+// DeletePreset deletes the processing preset with the given name
+func (a *App) DeletePreset(name string) PresetsResponse {
+	if err := a.presets.Delete(name); err != nil {
+		return PresetsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to delete preset: %v", err),
+		}
+	}
+	return a.presetsResponse()
+}
+
+// presetsResponse lists the saved presets
+func (a *App) presetsResponse() PresetsResponse {
+	presets, err := a.presets.List()
+	if err != nil {
+		return PresetsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to load presets: %v", err),
+		}
+	}
+	return PresetsResponse{
+		Success: true,
+		Presets: presets,
+	}
+}
+
+// GetFrames returns the built-in frame templates and those in the frames
+// directory
+func (a *App) GetFrames() FramesResponse {
+	frames, err := a.frames.List()
+	if err != nil {
+		return FramesResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to load frames: %v", err),
+		}
+	}
+	return FramesResponse{
+		Success: true,
+		Frames:  frames,
+	}
+}
+
+// GetImageMetadata reads the world, author and capture time embedded in a
+// screenshot, or the capture time in its VRChat filename. A world that isn't
+// embedded is looked up in the VRChat logs.
+func (a *App) GetImageMetadata(imagePath string) ImageMetadataResponse {
+	m, err := upload.ExtractMetadata(imagePath)
+	if err != nil {
+		return ImageMetadataResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	response := ImageMetadataResponse{
+		Success:   true,
+		WorldID:   m.WorldID,
+		WorldName: m.WorldName,
+		Author:    m.Author,
+	}
+	if m.CaptureTime.IsZero() {
+		return response
+	}
+	response.CaptureTime = m.CaptureTime.Local().Format(captureTimeLayout)
+
+	// Older screenshots have no world, so suggest the one the logs say we
+	// were in when it was taken
+	if m.WorldID == "" {
+		visit, err := a.logs.Lookup(m.CaptureTime)
+		if err != nil {
+			if !errors.Is(err, vrclog.ErrNotFound) {
+				a.logger.Warn("failed to read VRChat logs", "error", err)
+			}
+			return response
+		}
+		response.WorldID = visit.WorldID
+		if response.WorldName == "" {
+			response.WorldName = visit.WorldName
+		}
+		response.WorldFromLog = true
+	}
+	return response
+}
+
+// CancelUpload aborts the upload or export in progress, if any
+func (a *App) CancelUpload() bool {
+	a.uploadMu.Lock()
+	defer a.uploadMu.Unlock()
+
+	if a.cancelUpload == nil {
+		return false
+	}
+	a.cancelUpload()
+	return true
+}
+
+// setUploadCancel records the cancel function of the running upload or
+// export. It reports false if one is already running.
+func (a *App) setUploadCancel(cancel context.CancelFunc) bool {
+	a.uploadMu.Lock()
+	defer a.uploadMu.Unlock()
+
+	if cancel != nil && a.cancelUpload != nil {
+		return false
+	}
+	a.cancelUpload = cancel
+	return true
+}
+
+// GetRateLimitStatus returns how long the API wants us to wait before the next request
+func (a *App) GetRateLimitStatus() RateLimitStatusResponse {
+	if a.apiClient == nil {
+		return RateLimitStatusResponse{Limit: -1, Remaining: -1}
+	}
+
+	now := time.Now()
+	status := a.apiClient.RateLimit()
+
+	resp := RateLimitStatusResponse{
+		Limited:           status.Limited(now),
+		RetryAfterSeconds: int(math.Ceil(status.WaitDuration(now).Seconds())),
+		Limit:             status.Limit,
+		Remaining:         status.Remaining,
+	}
+	if !status.ResetAt.IsZero() {
+		resp.ResetAt = status.ResetAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// GetCircuitBreakerStatus returns whether requests to the API are currently being failed fast
+func (a *App) GetCircuitBreakerStatus() CircuitBreakerStatusResponse {
+	if a.apiClient == nil {
+		return CircuitBreakerStatusResponse{State: string(client.BreakerClosed), Available: true}
+	}
+
+	status := a.apiClient.Breaker()
+	resp := CircuitBreakerStatusResponse{
+		State:               string(status.State),
+		Available:           status.State != client.BreakerOpen,
+		ConsecutiveFailures: status.ConsecutiveFailures,
+	}
+	if status.State == client.BreakerOpen {
+		resp.RetryAfterSeconds = int(math.Ceil(time.Until(status.RetryAt).Seconds()))
+	}
+	return resp
 }
 
 // OpenFileDialog opens a file dialog and returns the selected file path
@@ -263,7 +940,7 @@ func (a *App) OpenFileDialog() (string, error) {
 		Filters: []runtime.FileFilter{
 			{
 				DisplayName: "Image Files",
-				Pattern:     "*.png;*.jpg;*.jpeg;*.gif",
+				Pattern:     "*" + strings.Join(upload.SupportedExtensions, ";*"),
 			},
 			{
 				DisplayName: "PNG Files",
@@ -277,6 +954,18 @@ func (a *App) OpenFileDialog() (string, error) {
 				DisplayName: "GIF Files",
 				Pattern:     "*.gif",
 			},
+			{
+				DisplayName: "WebP Files",
+				Pattern:     "*.webp",
+			},
+			{
+				DisplayName: "BMP Files",
+				Pattern:     "*.bmp",
+			},
+			{
+				DisplayName: "TIFF Files",
+				Pattern:     "*.tif;*.tiff",
+			},
 		},
 	})
 
@@ -287,30 +976,55 @@ func (a *App) OpenFileDialog() (string, error) {
 	return filePath, nil
 }
 
-// ValidateImageFile validates if a file is a supported image format
-func (a *App) ValidateImageFile(filePath string) map[string]interface{} {
-	if filePath == "" {
-		return map[string]interface{}{
-			"valid": false,
-			"error": "No file path provided",
+// OpenDirectoryDialog opens a directory selection dialog, for choosing where
+// processed images are exported or prints are backed up
+func (a *App) OpenDirectoryDialog() (string, error) {
+	dir, err := runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:                "Select Directory",
+		CanCreateDirectories: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open directory dialog: %w", err)
+	}
+	return dir, nil
+}
+
+// ValidateImageFile inspects the selected file by its content and predicts
+// the size of the uploaded image with the requested options
+func (a *App) ValidateImageFile(req UploadRequest) ImageValidationResponse {
+	opts, err := a.uploadOptions(req)
+	if err != nil {
+		return ImageValidationResponse{
+			Valid: false,
+			Error: err.Error(),
 		}
 	}
 
-	ext := filepath.Ext(filePath)
-	supportedExts := []string{".png", ".jpg", ".jpeg", ".gif"}
-	
-	for _, supportedExt := range supportedExts {
-		if ext == supportedExt {
-			return map[string]interface{}{
-				"valid": true,
-				"type":  ext,
-			}
+	info, err := upload.Inspect(opts)
+	if err != nil {
+		return ImageValidationResponse{
+			Valid: false,
+			Error: fmt.Sprintf("Invalid image file: %v", err),
+		}
+	}
+
+	if info.FileSize > upload.MaxImageSize {
+		return ImageValidationResponse{
+			Valid: false,
+			Error: fmt.Sprintf("Image file too large: %d bytes (max: %d bytes)", info.FileSize, upload.MaxImageSize),
 		}
 	}
 
-	return map[string]interface{}{
-		"valid": false,
-		"error": fmt.Sprintf("Unsupported file format: %s", ext),
+	return ImageValidationResponse{
+		Valid:        true,
+		Format:       info.Format,
+		Width:        info.Width,
+		Height:       info.Height,
+		FileSize:     info.FileSize,
+		ColorModel:   info.ColorModel,
+		Frames:       info.Frames,
+		OutputWidth:  info.OutputWidth,
+		OutputHeight: info.OutputHeight,
 	}
 }
 
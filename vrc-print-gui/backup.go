@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/yoshiken/vrc-print-upload/internal/backup"
+)
+
+// BackupProgressEvent is emitted as "backup:progress" while a backup runs
+type BackupProgressEvent struct {
+	Phase   string `json:"phase"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	PrintID string `json:"printId,omitempty"`
+	// WaitSeconds is how long the backup waits for the rate limit
+	WaitSeconds int `json:"waitSeconds,omitempty"`
+}
+
+// BackupResponse summarizes a backup run
+type BackupResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Dir is the archive directory
+	Dir        string `json:"dir"`
+	Listed     int    `json:"listed"`
+	Downloaded int    `json:"downloaded"`
+	Skipped    int    `json:"skipped"`
+	// Failed is the number of images that couldn't be downloaded; the
+	// next run tries them again
+	Failed   int   `json:"failed"`
+	Archived int   `json:"archived"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// BackupDirResponse carries the default backup directory
+type BackupDirResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+}
+
+// GetBackupDir returns the directory prints are backed up to when no other
+// directory is chosen. Each user has their own, so it needs a login.
+func (a *App) GetBackupDir() BackupDirResponse {
+	if a.printService == nil {
+		return BackupDirResponse{
+			Success: false,
+			Error:   "Not authenticated. Please log in first.",
+		}
+	}
+
+	userID, err := a.currentUserID()
+	if err != nil {
+		return BackupDirResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to get user info: %v", err),
+		}
+	}
+	return BackupDirResponse{
+		Success: true,
+		Dir:     a.backupDir(userID),
+	}
+}
+
+// backupDir is the default backup directory of the user
+func (a *App) backupDir(userID string) string {
+	return filepath.Join(a.config.BackupDir(), userID)
+}
+
+// BackupPrints downloads every print of the logged in user that isn't in the
+// archive at dir yet, or in GetBackupDir if dir is empty, and updates the
+// manifest. Runs can be cancelled and resume where they stopped.
+func (a *App) BackupPrints(dir string) BackupResponse {
+	if a.printService == nil {
+		return BackupResponse{
+			Success: false,
+			Error:   "Not authenticated. Please log in first.",
+		}
+	}
+
+	userID, err := a.currentUserID()
+	if err != nil {
+		return BackupResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to get user info: %v", err),
+		}
+	}
+	if dir == "" {
+		dir = a.backupDir(userID)
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+	if !a.setBackupCancel(cancel) {
+		return BackupResponse{
+			Success: false,
+			Error:   "A backup is already running",
+		}
+	}
+	defer a.setBackupCancel(nil)
+
+	archive := backup.NewArchive(dir, a.printService)
+	result, err := archive.Sync(ctx, userID, a.emitBackupProgress)
+
+	response := BackupResponse{Dir: dir}
+	if result != nil {
+		response.Listed = result.Listed
+		response.Downloaded = result.Downloaded
+		response.Skipped = result.Skipped
+		response.Failed = len(result.Failed)
+		response.Archived = result.Archived
+		response.Bytes = result.Bytes
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			response.Error = "Backup cancelled"
+		} else {
+			response.Error = fmt.Sprintf("Backup failed: %v", err)
+		}
+		return response
+	}
+	for id, err := range result.Failed {
+		a.logger.Warn("failed to back up print", "print_id", id, "error", err)
+	}
+
+	response.Success = true
+	return response
+}
+
+// CancelBackup stops the running backup. What was downloaded so far stays
+// in the archive.
+func (a *App) CancelBackup() bool {
+	a.backupMu.Lock()
+	defer a.backupMu.Unlock()
+
+	if a.cancelBackup == nil {
+		return false
+	}
+	a.cancelBackup()
+	return true
+}
+
+// setBackupCancel records the cancel function of the running backup. It
+// reports false if another backup is already running.
+func (a *App) setBackupCancel(cancel context.CancelFunc) bool {
+	a.backupMu.Lock()
+	defer a.backupMu.Unlock()
+
+	if cancel != nil && a.cancelBackup != nil {
+		return false
+	}
+	a.cancelBackup = cancel
+	return true
+}
+
+// emitBackupProgress forwards backup progress to the frontend
+func (a *App) emitBackupProgress(p backup.Progress) {
+	runtime.EventsEmit(a.ctx, "backup:progress", BackupProgressEvent{
+		Phase:       string(p.Phase),
+		Done:        p.Done,
+		Total:       p.Total,
+		PrintID:     p.PrintID,
+		WaitSeconds: int(math.Ceil(p.Wait.Seconds())),
+	})
+}
@@ -2,20 +2,70 @@ import './style.css';
 
 // Import Wails runtime and Go functions
 import {
+    CancelUpload,
+    GetCircuitBreakerStatus,
     IsAuthenticated,
     Login,
     VerifyTwoFactor,
     Logout,
     GetCurrentUser,
+    GetRateLimitStatus,
     UploadImage,
     ValidateImageFile,
-    OpenFileDialog
+    OpenFileDialog,
+    PreviewCrop,
+    GetPresets,
+    SavePreset,
+    DeletePreset,
+    GetFrames,
+    GetImageMetadata,
+    ExportImage,
+    OpenDirectoryDialog,
+    PreviewImage,
+    ListPrints,
+    GetPrintImage,
+    DeletePrint,
+    BackupPrints,
+    CancelBackup
 } from '../wailsjs/go/main/App';
+import { EventsOn, EventsOff } from '../wailsjs/runtime/runtime';
 
 // Application state
 let currentUser = null;
 let selectedFile = null;
 let selectedFilePath = null;
+let focalPoint = { x: 0.5, y: 0.5 };
+let filters = [];
+let presets = [];
+let frames = [];
+let galleryOffset = 0;
+
+// Parameters of each filter type. Values are shown multiplied by scale, so
+// that normalized crop rectangles can be edited as percentages.
+const FILTER_TYPES = {
+    rotate: { label: '回転', params: [{ key: 'angle', label: '角度（時計回り）', value: 90, min: -360, max: 360, step: 0.5 }] },
+    crop: {
+        label: '切り抜き',
+        params: [
+            { key: 'x', label: '左 (%)', value: 0, min: 0, max: 100, step: 1, scale: 100 },
+            { key: 'y', label: '上 (%)', value: 0, min: 0, max: 100, step: 1, scale: 100 },
+            { key: 'width', label: '幅 (%)', value: 1, min: 1, max: 100, step: 1, scale: 100 },
+            { key: 'height', label: '高さ (%)', value: 1, min: 1, max: 100, step: 1, scale: 100 }
+        ]
+    },
+    resize: {
+        label: 'リサイズ',
+        params: [
+            { key: 'width', label: '幅 (px, 0で自動)', value: 1920, min: 0, max: 8192, step: 1 },
+            { key: 'height', label: '高さ (px, 0で自動)', value: 0, min: 0, max: 8192, step: 1 }
+        ]
+    },
+    brightness: { label: '明るさ', params: [{ key: 'amount', label: '%', value: 10, min: -100, max: 100, step: 1 }] },
+    contrast: { label: 'コントラスト', params: [{ key: 'amount', label: '%', value: 10, min: -100, max: 100, step: 1 }] },
+    saturation: { label: '彩度', params: [{ key: 'amount', label: '%', value: 10, min: -100, max: 100, step: 1 }] },
+    sharpen: { label: 'シャープ', params: [{ key: 'amount', label: '強さ (sigma)', value: 1, min: 0.1, max: 50, step: 0.1 }] },
+    blur: { label: 'ぼかし', params: [{ key: 'amount', label: '強さ (sigma)', value: 1, min: 0.1, max: 50, step: 0.1 }] }
+};
 
 // Initialize the application
 document.addEventListener('DOMContentLoaded', async () => {
@@ -51,6 +101,15 @@ function setupEventListeners() {
         loginForm.addEventListener('submit', handleLogin);
     }
     
+    // Dry runs don't need an account
+    const offlineBtn = document.getElementById('offline-btn');
+    if (offlineBtn) {
+        offlineBtn.addEventListener('click', () => {
+            currentUser = null;
+            showMainScreen();
+        });
+    }
+    
     // 2FA verification
     const verify2FABtn = document.getElementById('verify-2fa-btn');
     if (verify2FABtn) {
@@ -63,6 +122,30 @@ function setupEventListeners() {
         logoutBtn.addEventListener('click', handleLogout);
     }
     
+    // Gallery
+    const galleryRefreshBtn = document.getElementById('gallery-refresh-btn');
+    if (galleryRefreshBtn) {
+        galleryRefreshBtn.addEventListener('click', () => loadGallery());
+    }
+    const galleryMoreBtn = document.getElementById('gallery-more-btn');
+    if (galleryMoreBtn) {
+        galleryMoreBtn.addEventListener('click', () => loadGallery(true));
+    }
+    
+    // Backup
+    const backupBtn = document.getElementById('backup-btn');
+    if (backupBtn) {
+        backupBtn.addEventListener('click', () => handleBackup(''));
+    }
+    const backupChooseBtn = document.getElementById('backup-choose-btn');
+    if (backupChooseBtn) {
+        backupChooseBtn.addEventListener('click', handleBackupChoose);
+    }
+    const backupCancelBtn = document.getElementById('backup-cancel-btn');
+    if (backupCancelBtn) {
+        backupCancelBtn.addEventListener('click', () => CancelBackup());
+    }
+    
     // File selection
     const fileSelectBtn = document.getElementById('file-select-btn');
     const fileInput = document.getElementById('file-input');
@@ -91,6 +174,101 @@ function setupEventListeners() {
         uploadBtn.addEventListener('click', handleUpload);
     }
     
+    // Processed image preview
+    const processedPreviewBtn = document.getElementById('processed-preview-btn');
+    if (processedPreviewBtn) {
+        processedPreviewBtn.addEventListener('click', handleProcessedPreview);
+    }
+    
+    // Export button
+    const exportBtn = document.getElementById('export-btn');
+    if (exportBtn) {
+        exportBtn.addEventListener('click', handleExport);
+    }
+    
+    // Cancel upload button
+    const cancelUploadBtn = document.getElementById('cancel-upload-btn');
+    if (cancelUploadBtn) {
+        cancelUploadBtn.addEventListener('click', handleCancelUpload);
+    }
+    
+    // Resize mode: the pad color only applies to "fit", the crop to "fill"
+    document.querySelectorAll('input[name="resize"]').forEach(radio => {
+        radio.addEventListener('change', updateResizeOptions);
+    });
+    document.querySelectorAll('input[name="crop-anchor"]').forEach(radio => {
+        radio.addEventListener('change', updateCropPreview);
+    });
+    
+    // The background color only applies when transparency is flattened
+    const preserveAlpha = document.getElementById('preserve-alpha');
+    if (preserveAlpha) {
+        preserveAlpha.addEventListener('change', updateAlphaOptions);
+    }
+    
+    // Orientation changes move the crop and can change the output size
+    ['rotate', 'flip-horizontal', 'flip-vertical'].forEach(id => {
+        const input = document.getElementById(id);
+        if (input) {
+            input.addEventListener('change', () => {
+                refreshFileDetails();
+                updateCropPreview();
+            });
+        }
+    });
+    
+    // Frames
+    const frameSelect = document.getElementById('frame-select');
+    if (frameSelect) {
+        frameSelect.addEventListener('change', updateFrameDescription);
+    }
+    
+    // Watermarks
+    ['text', 'image'].forEach(kind => {
+        const enabled = document.getElementById(`watermark-${kind}-enabled`);
+        if (enabled) {
+            enabled.addEventListener('change', updateWatermarkOptions);
+        }
+    });
+    const watermarkImageBtn = document.getElementById('watermark-image-btn');
+    if (watermarkImageBtn) {
+        watermarkImageBtn.addEventListener('click', handleWatermarkImageSelect);
+    }
+    
+    // Filter pipeline editor
+    const filterAddBtn = document.getElementById('filter-add-btn');
+    if (filterAddBtn) {
+        filterAddBtn.addEventListener('click', handleAddFilter);
+    }
+    
+    // Print timestamp
+    const timestampFromCaptureBtn = document.getElementById('timestamp-from-capture');
+    if (timestampFromCaptureBtn) {
+        timestampFromCaptureBtn.addEventListener('click', () => {
+            document.getElementById('timestamp').value = document.getElementById('capture-time').value;
+        });
+    }
+    
+    // Presets
+    const presetSelect = document.getElementById('preset-select');
+    if (presetSelect) {
+        presetSelect.addEventListener('change', handlePresetSelect);
+    }
+    const presetSaveBtn = document.getElementById('preset-save-btn');
+    if (presetSaveBtn) {
+        presetSaveBtn.addEventListener('click', handlePresetSave);
+    }
+    const presetDeleteBtn = document.getElementById('preset-delete-btn');
+    if (presetDeleteBtn) {
+        presetDeleteBtn.addEventListener('click', handlePresetDelete);
+    }
+    
+    // Clicking the crop preview sets the focal point
+    const cropPreviewImage = document.getElementById('crop-preview-image');
+    if (cropPreviewImage) {
+        cropPreviewImage.addEventListener('click', handleCropPreviewClick);
+    }
+    
     // Recovery code checkbox
     const recoveryCheckbox = document.getElementById('recovery-code');
     const twoFactorInput = document.getElementById('two-factor-code');
@@ -161,8 +339,8 @@ async function handleFileSelect(e) {
 
 async function processSelectedFilePath(filePath) {
     try {
-        // Validate file
-        const validation = await ValidateImageFile(filePath);
+        // Validate file by its content
+        const validation = await ValidateImageFile({ ...buildUploadRequest(), imagePath: filePath });
         
         if (!validation.valid) {
             showStatusMessage('error', validation.error || 'Invalid file selected');
@@ -174,12 +352,13 @@ async function processSelectedFilePath(filePath) {
         
         // Show file info
         displaySelectedFilePath(filePath);
+        displayFileDetails(validation);
+        await prefillFromMetadata(filePath);
+        focalPoint = { x: 0.5, y: 0.5 };
+        updateCropPreview();
         
-        // Enable upload button
-        const uploadBtn = document.getElementById('upload-btn');
-        if (uploadBtn) {
-            uploadBtn.disabled = false;
-        }
+        // Enable upload and export buttons
+        setUploadButtonsEnabled(true);
         
     } catch (error) {
         console.error('Error processing file:', error);
@@ -187,13 +366,41 @@ async function processSelectedFilePath(filePath) {
     }
 }
 
+// Fill the world and capture time from the screenshot's embedded metadata
+// or its VRChat filename, falling back to the world the VRChat logs record
+// at the capture time. Fields nothing is known about are kept.
+async function prefillFromMetadata(filePath) {
+    const worldHint = document.getElementById('world-hint');
+    worldHint.classList.add('hidden');
+    document.getElementById('capture-time').value = '';
+    try {
+        const metadata = await GetImageMetadata(filePath);
+        if (!metadata.success) {
+            console.error('Failed to read image metadata:', metadata.error);
+            return;
+        }
+        if (metadata.worldId) {
+            document.getElementById('world-id').value = metadata.worldId;
+        }
+        if (metadata.worldName) {
+            document.getElementById('world-name').value = metadata.worldName;
+        }
+        if (metadata.captureTime) {
+            document.getElementById('capture-time').value = metadata.captureTime;
+        }
+        if (metadata.worldFromLog) {
+            worldHint.classList.remove('hidden');
+        }
+    } catch (error) {
+        console.error('Failed to read image metadata:', error);
+    }
+}
+
 async function processSelectedFile(file) {
     try {
-        // Validate file
-        const validation = await ValidateImageFile(file.name);
-        
-        if (!validation.valid) {
-            showStatusMessage('error', validation.error || 'Invalid file selected');
+        // Only the file name is available, so check the type the browser reports
+        if (!file.type || !file.type.startsWith('image/')) {
+            showStatusMessage('error', `Unsupported file type: ${file.type || file.name}`);
             return;
         }
         
@@ -206,11 +413,8 @@ async function processSelectedFile(file) {
         // Show warning that drag & drop won't work for upload
         showStatusMessage('warning', 'ドラッグ&ドロップが検出されました。実際のアップロードには「画像ファイルを選択」ボタンをご利用ください。');
         
-        // Enable upload button but it will show error
-        const uploadBtn = document.getElementById('upload-btn');
-        if (uploadBtn) {
-            uploadBtn.disabled = false;
-        }
+        // Enable upload and export buttons but they will show an error
+        setUploadButtonsEnabled(true);
         
     } catch (error) {
         console.error('Error processing file:', error);
@@ -267,14 +471,16 @@ function clearSelectedFile() {
     
     const fileInfo = document.getElementById('file-info');
     const dropZone = document.getElementById('drop-zone');
-    const uploadBtn = document.getElementById('upload-btn');
     const fileInput = document.getElementById('file-input');
     
     if (fileInfo) fileInfo.classList.add('hidden');
     if (dropZone) dropZone.style.display = 'block';
-    if (uploadBtn) uploadBtn.disabled = true;
+    const fileMeta = document.getElementById('file-meta');
+    if (fileMeta) fileMeta.textContent = '';
+    setUploadButtonsEnabled(false);
     if (fileInput) fileInput.value = '';
     
+    updateCropPreview();
     clearStatusMessage();
 }
 
@@ -353,6 +559,11 @@ async function handleTwoFactorVerification() {
 }
 
 async function handleLogout() {
+    if (!currentUser) {
+        showLoginScreen();
+        return;
+    }
+    
     try {
         const response = await Logout();
         
@@ -377,24 +588,18 @@ async function handleUpload() {
         return;
     }
     
+    // Don't spend a request while the API asks us to wait or is down
+    if (await showRateLimitWarning() || await showAPIUnavailableWarning()) {
+        return;
+    }
+    
     const uploadBtn = document.getElementById('upload-btn');
     const progressContainer = document.getElementById('upload-progress');
     const progressFill = document.getElementById('progress-fill');
     const progressText = document.getElementById('progress-text');
     
     // Get form data
-    const resizeOption = document.querySelector('input[name="resize"]:checked').value;
-    const note = document.getElementById('note').value.trim();
-    const worldId = document.getElementById('world-id').value.trim();
-    const worldName = document.getElementById('world-name').value.trim();
-    
-    const uploadRequest = {
-        imagePath: selectedFilePath,
-        note: note,
-        worldId: worldId,
-        worldName: worldName,
-        noResize: resizeOption === 'keep'
-    };
+    const uploadRequest = buildUploadRequest();
     
     // Show progress
     setButtonLoading(uploadBtn, true);
@@ -405,23 +610,20 @@ async function handleUpload() {
     }
     
     try {
-        // Simulate progress (since we don't have real-time progress from Go)
-        const progressInterval = setInterval(() => {
-            const currentWidth = parseFloat(progressFill.style.width) || 0;
-            if (currentWidth < 90) {
-                progressFill.style.width = (currentWidth + 10) + '%';
-            }
-        }, 200);
+        // Follow the real progress reported by the Go side
+        EventsOn('upload:progress', (progress) => updateUploadProgress(progress));
         
         const response = await UploadImage(uploadRequest);
         
-        clearInterval(progressInterval);
+        EventsOff('upload:progress');
         
         if (response.success) {
             progressFill.style.width = '100%';
             progressText.textContent = 'アップロード完了！';
             
-            showStatusMessage('success', `アップロードに成功しました！ファイルID: ${response.fileId}`);
+            showStatusMessage('success', `アップロードに成功しました！ファイルID: ${response.fileId}${describeEncoding(response)}`);
+            
+            loadGallery();
             
             // Clear form after successful upload
             setTimeout(() => {
@@ -432,9 +634,11 @@ async function handleUpload() {
         } else {
             showStatusMessage('error', response.error || 'アップロードに失敗しました');
             if (progressContainer) progressContainer.classList.add('hidden');
+            await showRateLimitWarning();
         }
     } catch (error) {
         console.error('Upload error:', error);
+        EventsOff('upload:progress');
         showStatusMessage('error', 'アップロードに失敗しました。再度お試しください。');
         if (progressContainer) progressContainer.classList.add('hidden');
     } finally {
@@ -442,11 +646,663 @@ async function handleUpload() {
     }
 }
 
+// handleProcessedPreview shows the image exactly as it would be uploaded
+// with the current options
+async function handleProcessedPreview() {
+    if (!selectedFilePath) {
+        showStatusMessage('error', '画像ファイルを選択してください');
+        return;
+    }
+    
+    const button = document.getElementById('processed-preview-btn');
+    const container = document.getElementById('processed-preview');
+    setButtonLoading(button, true);
+    try {
+        const preview = await PreviewImage(buildUploadRequest());
+        if (!preview.success) {
+            container.classList.add('hidden');
+            showStatusMessage('error', preview.error || 'プレビューを作成できませんでした');
+            return;
+        }
+        document.getElementById('processed-preview-image').src = preview.image;
+        document.getElementById('processed-preview-info').textContent =
+            `${preview.width}×${preview.height}、${formatBytes(preview.size)}${describeEncoding({ strategy: preview.strategy })}`;
+        container.classList.remove('hidden');
+    } catch (error) {
+        console.error('Preview error:', error);
+        showStatusMessage('error', 'プレビューを作成できませんでした');
+    } finally {
+        setButtonLoading(button, false);
+    }
+}
+
+// handleExport runs the upload processing and writes the PNG and the form
+// fields to a chosen directory instead of uploading them
+async function handleExport() {
+    if (!selectedFilePath) {
+        showStatusMessage('error', '画像ファイルを選択してください');
+        return;
+    }
+    
+    let dir;
+    try {
+        dir = await OpenDirectoryDialog();
+    } catch (error) {
+        console.error('Error opening directory dialog:', error);
+        showStatusMessage('error', 'フォルダ選択ダイアログを開けませんでした');
+        return;
+    }
+    if (!dir) return;
+    
+    const exportBtn = document.getElementById('export-btn');
+    const progressContainer = document.getElementById('upload-progress');
+    const progressFill = document.getElementById('progress-fill');
+    const progressText = document.getElementById('progress-text');
+    
+    setButtonLoading(exportBtn, true);
+    if (progressContainer) {
+        progressContainer.classList.remove('hidden');
+        progressFill.style.width = '0%';
+        progressText.textContent = '書き出し準備中...';
+    }
+    
+    try {
+        EventsOn('upload:progress', (progress) => updateUploadProgress(progress));
+        const response = await ExportImage(buildUploadRequest(), dir);
+        EventsOff('upload:progress');
+        
+        if (response.success) {
+            progressFill.style.width = '100%';
+            progressText.textContent = '書き出し完了！';
+            showStatusMessage('success', `処理済み画像を書き出しました: ${response.exportPath}（送信内容: ${response.manifestPath}）${describeEncoding(response)}`);
+        } else {
+            showStatusMessage('error', response.error || '書き出しに失敗しました');
+        }
+    } catch (error) {
+        console.error('Export error:', error);
+        EventsOff('upload:progress');
+        showStatusMessage('error', '書き出しに失敗しました。再度お試しください。');
+    } finally {
+        setTimeout(() => {
+            if (progressContainer) progressContainer.classList.add('hidden');
+        }, 2000);
+        setButtonLoading(exportBtn, false);
+    }
+}
+
+function setUploadButtonsEnabled(enabled) {
+    if (!enabled) {
+        document.getElementById('processed-preview').classList.add('hidden');
+    }
+    ['upload-btn', 'export-btn', 'processed-preview-btn'].forEach(id => {
+        const button = document.getElementById(id);
+        if (button) button.disabled = !enabled;
+    });
+}
+
+// Share of the progress bar each phase occupies, as [start, end] percent
+const uploadPhaseRanges = {
+    decode: [0, 10],
+    resize: [10, 20],
+    encode: [20, 30],
+    upload: [30, 100]
+};
+
+const uploadPhaseLabels = {
+    decode: '画像を読み込み中...',
+    resize: 'リサイズ中...',
+    encode: 'PNGに変換中...',
+    upload: 'アップロード中...'
+};
+
+function updateUploadProgress(progress) {
+    const progressFill = document.getElementById('progress-fill');
+    const progressText = document.getElementById('progress-text');
+    const range = uploadPhaseRanges[progress.phase];
+    if (!progressFill || !progressText || !range) return;
+    
+    const fraction = progress.total > 0 ? Math.min(progress.bytesSent / progress.total, 1) : 0;
+    progressFill.style.width = (range[0] + (range[1] - range[0]) * fraction) + '%';
+    
+    let text = uploadPhaseLabels[progress.phase];
+    if (progress.phase === 'upload' && progress.total > 0) {
+        text += ` ${formatBytes(progress.bytesSent)} / ${formatBytes(progress.total)}`;
+        if (progress.rate > 0) {
+            text += ` (${formatBytes(progress.rate)}/s)`;
+        }
+    }
+    progressText.textContent = text;
+}
+
+// describeEncoding explains how the PNG was shrunk to fit, if it was
+function describeEncoding(response) {
+    const size = response.size ? `（${response.width}×${response.height}、${formatBytes(response.size)}）` : '';
+    switch (response.strategy) {
+        case 'best-compression':
+            return ` 最高圧縮でエンコードしました${size}`;
+        case 'quantize':
+            return ` 256色に減色してサイズを削減しました${size}`;
+        case 'downscale':
+            return ` サイズ上限に収めるため解像度を下げました${size}`;
+        default:
+            return '';
+    }
+}
+
+function formatBytes(bytes) {
+    if (bytes >= 1024 * 1024) return (bytes / (1024 * 1024)).toFixed(1) + 'MB';
+    if (bytes >= 1024) return (bytes / 1024).toFixed(1) + 'KB';
+    return Math.round(bytes) + 'B';
+}
+
+async function handleCancelUpload() {
+    try {
+        const cancelled = await CancelUpload();
+        if (cancelled) {
+            const progressText = document.getElementById('progress-text');
+            if (progressText) progressText.textContent = 'キャンセル中...';
+        }
+    } catch (error) {
+        console.error('Cancel upload error:', error);
+    }
+}
+
+// showRateLimitWarning shows the remaining wait time if the API rate limit
+// is active and returns whether uploads are currently held back
+async function showRateLimitWarning() {
+    try {
+        const status = await GetRateLimitStatus();
+        if (status.limited) {
+            showStatusMessage('warning', `レート制限中です。次のアップロードまであと${status.retryAfterSeconds}秒お待ちください`);
+            return true;
+        }
+    } catch (error) {
+        console.error('Failed to get rate limit status:', error);
+    }
+    return false;
+}
+
+// showAPIUnavailableWarning shows a message while the circuit breaker
+// fails requests fast and returns whether uploads are currently blocked
+async function showAPIUnavailableWarning() {
+    try {
+        const status = await GetCircuitBreakerStatus();
+        if (!status.available) {
+            showStatusMessage('warning', `VRChat APIが応答していません。${status.retryAfterSeconds}秒後に再試行できます`);
+            return true;
+        }
+    } catch (error) {
+        console.error('Failed to get API status:', error);
+    }
+    return false;
+}
+
 function clearForm() {
     document.getElementById('note').value = '';
     document.getElementById('world-id').value = '';
     document.getElementById('world-name').value = '';
-    document.querySelector('input[name="resize"][value="resize"]').checked = true;
+    document.getElementById('capture-time').value = '';
+    document.getElementById('world-hint').classList.add('hidden');
+    document.getElementById('timestamp').value = '';
+    document.getElementById('time-zone').value = '';
+    document.querySelector('input[name="resize"][value="fit"]').checked = true;
+    document.getElementById('pad-color').value = '#000000';
+    document.querySelector('input[name="crop-anchor"][value="center"]').checked = true;
+    focalPoint = { x: 0.5, y: 0.5 };
+    document.getElementById('rotate').value = '0';
+    document.getElementById('flip-horizontal').checked = false;
+    document.getElementById('flip-vertical').checked = false;
+    document.getElementById('shrink').checked = false;
+    document.getElementById('quantize').checked = false;
+    document.getElementById('preserve-alpha').checked = false;
+    document.getElementById('background-color').value = '#ffffff';
+    document.getElementById('preset-select').value = '';
+    filters = [];
+    renderFilters();
+    document.getElementById('frame-select').value = '';
+    updateFrameDescription();
+    setWatermarks([]);
+    updateResizeOptions();
+    updateAlphaOptions();
+}
+
+// dateTimeValue returns the value of a datetime-local input with seconds,
+// which the input leaves out when they are zero
+function dateTimeValue(id) {
+    const value = document.getElementById(id).value;
+    return value.length === 16 ? `${value}:00` : value;
+}
+
+function buildUploadRequest() {
+    const resizeMode = document.querySelector('input[name="resize"]:checked').value;
+    const cropAnchor = document.querySelector('input[name="crop-anchor"]:checked').value;
+    const preserveAlpha = document.getElementById('preserve-alpha').checked;
+    
+    return {
+        imagePath: selectedFilePath,
+        note: document.getElementById('note').value.trim(),
+        worldId: document.getElementById('world-id').value.trim(),
+        worldName: document.getElementById('world-name').value.trim(),
+        captureTime: dateTimeValue('capture-time'),
+        timestamp: dateTimeValue('timestamp'),
+        timeZone: document.getElementById('time-zone').value,
+        resizeMode: resizeMode,
+        padColor: resizeMode === 'fit' ? document.getElementById('pad-color').value : '',
+        cropAnchor: resizeMode === 'fill' ? cropAnchor : '',
+        focalX: focalPoint.x,
+        focalY: focalPoint.y,
+        shrink: document.getElementById('shrink').checked,
+        quantize: document.getElementById('quantize').checked,
+        preserveAlpha: preserveAlpha,
+        backgroundColor: preserveAlpha ? '' : document.getElementById('background-color').value,
+        rotate: parseInt(document.getElementById('rotate').value, 10),
+        flipHorizontal: document.getElementById('flip-horizontal').checked,
+        flipVertical: document.getElementById('flip-vertical').checked,
+        filters: filters.map(f => ({ ...f })),
+        frame: document.getElementById('frame-select').value,
+        watermarks: buildWatermarks()
+    };
+}
+
+async function loadFrames() {
+    try {
+        const response = await GetFrames();
+        if (!response.success) {
+            console.error('Failed to load frames:', response.error);
+            return;
+        }
+        frames = response.frames || [];
+        renderFrameOptions();
+    } catch (error) {
+        console.error('Error loading frames:', error);
+    }
+}
+
+function renderFrameOptions() {
+    const select = document.getElementById('frame-select');
+    if (!select) return;
+    
+    const selected = select.value;
+    select.innerHTML = '';
+    const none = document.createElement('option');
+    none.value = '';
+    none.textContent = 'なし';
+    select.appendChild(none);
+    
+    frames.forEach(frame => {
+        const option = document.createElement('option');
+        option.value = frame.name;
+        option.textContent = frame.name;
+        select.appendChild(option);
+    });
+    select.value = frames.some(f => f.name === selected) ? selected : '';
+    updateFrameDescription();
+}
+
+function updateFrameDescription() {
+    const description = document.getElementById('frame-description');
+    if (!description) return;
+    
+    const frame = frames.find(f => f.name === document.getElementById('frame-select').value);
+    description.textContent = frame ? (frame.description || '') : '';
+}
+
+// Watermark defaults in percent, matching the form
+const WATERMARK_DEFAULTS = {
+    text: { margin: 2, opacity: 80, scale: 4 },
+    image: { margin: 2, opacity: 80, scale: 20 }
+};
+
+function buildWatermarks() {
+    const watermarks = [];
+    ['text', 'image'].forEach(kind => {
+        if (!document.getElementById(`watermark-${kind}-enabled`).checked) return;
+        
+        const percent = (name) => (parseFloat(document.getElementById(`watermark-${kind}-${name}`).value) || 0) / 100;
+        const watermark = {
+            position: document.getElementById(`watermark-${kind}-position`).value,
+            margin: percent('margin'),
+            opacity: percent('opacity'),
+            scale: percent('scale')
+        };
+        if (kind === 'text') {
+            watermark.text = document.getElementById('watermark-text').value.trim();
+            watermark.color = document.getElementById('watermark-text-color').value;
+            if (!watermark.text) return;
+        } else {
+            watermark.imagePath = document.getElementById('watermark-image-path').value;
+            if (!watermark.imagePath) return;
+        }
+        watermarks.push(watermark);
+    });
+    return watermarks;
+}
+
+// setWatermarks fills the form with the first text and image watermark
+function setWatermarks(watermarks) {
+    ['text', 'image'].forEach(kind => {
+        const watermark = watermarks.find(w => kind === 'text' ? w.text : w.imagePath);
+        const defaults = WATERMARK_DEFAULTS[kind];
+        const percent = (value, fallback) => value ? Math.round(value * 1000) / 10 : fallback;
+        
+        document.getElementById(`watermark-${kind}-enabled`).checked = !!watermark;
+        document.getElementById(`watermark-${kind}-position`).value = (watermark && watermark.position) || 'bottom-right';
+        document.getElementById(`watermark-${kind}-margin`).value = percent(watermark && watermark.margin, defaults.margin);
+        document.getElementById(`watermark-${kind}-opacity`).value = percent(watermark && watermark.opacity, defaults.opacity);
+        document.getElementById(`watermark-${kind}-scale`).value = percent(watermark && watermark.scale, defaults.scale);
+    });
+    
+    const text = watermarks.find(w => w.text);
+    document.getElementById('watermark-text').value = text ? text.text : '';
+    document.getElementById('watermark-text-color').value = (text && text.color) || '#ffffff';
+    const image = watermarks.find(w => w.imagePath);
+    document.getElementById('watermark-image-path').value = image ? image.imagePath : '';
+    updateWatermarkOptions();
+}
+
+function updateWatermarkOptions() {
+    ['text', 'image'].forEach(kind => {
+        const group = document.getElementById(`watermark-${kind}-group`);
+        if (group) {
+            group.classList.toggle('hidden', !document.getElementById(`watermark-${kind}-enabled`).checked);
+        }
+    });
+}
+
+async function handleWatermarkImageSelect() {
+    try {
+        const filePath = await OpenFileDialog();
+        if (filePath) {
+            document.getElementById('watermark-image-path').value = filePath;
+        }
+    } catch (error) {
+        console.error('Error opening file dialog:', error);
+        showStatusMessage('error', 'ファイルダイアログを開けませんでした');
+    }
+}
+
+function handleAddFilter() {
+    const type = document.getElementById('filter-type').value;
+    const filter = { type };
+    FILTER_TYPES[type].params.forEach(param => {
+        filter[param.key] = param.value;
+    });
+    filters.push(filter);
+    renderFilters();
+    onFiltersChanged();
+}
+
+function renderFilters() {
+    const list = document.getElementById('filter-list');
+    if (!list) return;
+    
+    list.innerHTML = '';
+    filters.forEach((filter, index) => {
+        const item = document.createElement('li');
+        item.className = 'filter-item';
+        
+        const name = document.createElement('span');
+        name.className = 'filter-name';
+        name.textContent = FILTER_TYPES[filter.type].label;
+        item.appendChild(name);
+        
+        FILTER_TYPES[filter.type].params.forEach(param => {
+            const scale = param.scale || 1;
+            const label = document.createElement('label');
+            label.className = 'filter-param';
+            label.textContent = param.label;
+            
+            const input = document.createElement('input');
+            input.type = 'number';
+            input.min = param.min;
+            input.max = param.max;
+            input.step = param.step;
+            input.value = Math.round((filter[param.key] || 0) * scale * 1000) / 1000;
+            input.addEventListener('change', () => {
+                filter[param.key] = (parseFloat(input.value) || 0) / scale;
+                onFiltersChanged();
+            });
+            label.appendChild(input);
+            item.appendChild(label);
+        });
+        
+        const actions = document.createElement('span');
+        actions.className = 'filter-actions';
+        [['↑', index - 1], ['↓', index + 1]].forEach(([text, target]) => {
+            const button = document.createElement('button');
+            button.type = 'button';
+            button.className = 'btn btn-small';
+            button.textContent = text;
+            button.disabled = target < 0 || target >= filters.length;
+            button.addEventListener('click', () => {
+                [filters[index], filters[target]] = [filters[target], filters[index]];
+                renderFilters();
+                onFiltersChanged();
+            });
+            actions.appendChild(button);
+        });
+        const removeButton = document.createElement('button');
+        removeButton.type = 'button';
+        removeButton.className = 'btn btn-small';
+        removeButton.textContent = '✕';
+        removeButton.addEventListener('click', () => {
+            filters.splice(index, 1);
+            renderFilters();
+            onFiltersChanged();
+        });
+        actions.appendChild(removeButton);
+        item.appendChild(actions);
+        
+        list.appendChild(item);
+    });
+}
+
+function onFiltersChanged() {
+    // Filters can change the output size and what the crop keeps
+    refreshFileDetails();
+    updateCropPreview();
+}
+
+async function loadPresets(selectedName = '') {
+    try {
+        const response = await GetPresets();
+        if (!response.success) {
+            console.error('Failed to load presets:', response.error);
+            return;
+        }
+        presets = response.presets || [];
+        renderPresetOptions(selectedName);
+    } catch (error) {
+        console.error('Error loading presets:', error);
+    }
+}
+
+function renderPresetOptions(selectedName) {
+    const select = document.getElementById('preset-select');
+    if (!select) return;
+    
+    select.innerHTML = '';
+    const placeholder = document.createElement('option');
+    placeholder.value = '';
+    placeholder.textContent = 'プリセットを選択...';
+    select.appendChild(placeholder);
+    
+    presets.forEach(preset => {
+        const option = document.createElement('option');
+        option.value = preset.name;
+        option.textContent = preset.name;
+        select.appendChild(option);
+    });
+    select.value = selectedName;
+}
+
+function handlePresetSelect(e) {
+    const preset = presets.find(p => p.name === e.target.value);
+    if (!preset) return;
+    
+    const resizeRadio = document.querySelector(`input[name="resize"][value="${preset.resizeMode || 'stretch'}"]`);
+    if (resizeRadio) resizeRadio.checked = true;
+    document.getElementById('pad-color').value = preset.padColor || '#000000';
+    const cropRadio = document.querySelector(`input[name="crop-anchor"][value="${preset.cropAnchor || 'center'}"]`);
+    if (cropRadio) cropRadio.checked = true;
+    document.getElementById('rotate').value = String(preset.rotate || 0);
+    document.getElementById('flip-horizontal').checked = preset.flipHorizontal;
+    document.getElementById('flip-vertical').checked = preset.flipVertical;
+    document.getElementById('preserve-alpha').checked = preset.preserveAlpha;
+    document.getElementById('background-color').value = preset.backgroundColor || '#ffffff';
+    document.getElementById('shrink').checked = preset.shrink;
+    document.getElementById('quantize').checked = preset.quantize;
+    document.getElementById('preset-name').value = preset.name;
+    
+    filters = (preset.filters || []).map(f => ({ ...f }));
+    renderFilters();
+    document.getElementById('frame-select').value = frames.some(f => f.name === preset.frame) ? preset.frame : '';
+    updateFrameDescription();
+    setWatermarks(preset.watermarks || []);
+    updateAlphaOptions();
+    updateResizeOptions();
+}
+
+async function handlePresetSave() {
+    const name = document.getElementById('preset-name').value.trim();
+    if (!name) {
+        showStatusMessage('error', 'プリセット名を入力してください');
+        return;
+    }
+    
+    const request = buildUploadRequest();
+    const response = await SavePreset({
+        name: name,
+        resizeMode: request.resizeMode,
+        padColor: document.getElementById('pad-color').value,
+        cropAnchor: document.querySelector('input[name="crop-anchor"]:checked').value,
+        rotate: request.rotate,
+        flipHorizontal: request.flipHorizontal,
+        flipVertical: request.flipVertical,
+        preserveAlpha: request.preserveAlpha,
+        backgroundColor: document.getElementById('background-color').value,
+        shrink: request.shrink,
+        quantize: request.quantize,
+        filters: request.filters,
+        frame: request.frame,
+        watermarks: request.watermarks
+    });
+    
+    if (response.success) {
+        presets = response.presets || [];
+        renderPresetOptions(name);
+        showStatusMessage('success', `プリセット「${name}」を保存しました`);
+    } else {
+        showStatusMessage('error', response.error || 'プリセットの保存に失敗しました');
+    }
+}
+
+async function handlePresetDelete() {
+    const name = document.getElementById('preset-select').value;
+    if (!name) {
+        showStatusMessage('error', '削除するプリセットを選択してください');
+        return;
+    }
+    
+    const response = await DeletePreset(name);
+    if (response.success) {
+        presets = response.presets || [];
+        renderPresetOptions('');
+        showStatusMessage('success', `プリセット「${name}」を削除しました`);
+    } else {
+        showStatusMessage('error', response.error || 'プリセットの削除に失敗しました');
+    }
+}
+
+function updateResizeOptions() {
+    const padColorGroup = document.getElementById('pad-color-group');
+    const resizeMode = document.querySelector('input[name="resize"]:checked').value;
+    if (padColorGroup) {
+        padColorGroup.classList.toggle('hidden', resizeMode !== 'fit');
+    }
+    refreshFileDetails();
+    updateCropPreview();
+}
+
+function updateAlphaOptions() {
+    const backgroundColorGroup = document.getElementById('background-color-group');
+    if (backgroundColorGroup) {
+        backgroundColorGroup.classList.toggle('hidden', document.getElementById('preserve-alpha').checked);
+    }
+}
+
+async function refreshFileDetails() {
+    // Files from drag & drop can't be inspected
+    if (!selectedFilePath || selectedFile) return;
+    
+    try {
+        const validation = await ValidateImageFile(buildUploadRequest());
+        if (validation.valid) {
+            displayFileDetails(validation);
+        }
+    } catch (error) {
+        console.error('Error inspecting file:', error);
+    }
+}
+
+function displayFileDetails(info) {
+    const fileMeta = document.getElementById('file-meta');
+    if (!fileMeta) return;
+    
+    let text = `${info.format.toUpperCase()} · ${info.width}×${info.height} · ${formatBytes(info.fileSize)}` +
+        ` → アップロード時 ${info.outputWidth}×${info.outputHeight}`;
+    if (info.frames > 1) {
+        text += `（アニメーション${info.frames}フレーム中、最初のフレームのみ）`;
+    }
+    fileMeta.textContent = text;
+}
+
+async function updateCropPreview() {
+    const cropGroup = document.getElementById('crop-group');
+    const resizeMode = document.querySelector('input[name="resize"]:checked').value;
+    if (!cropGroup) return;
+    
+    // Drag & drop only gives us a file name, which can't be previewed
+    if (resizeMode !== 'fill' || !selectedFilePath || selectedFile) {
+        cropGroup.classList.add('hidden');
+        return;
+    }
+    cropGroup.classList.remove('hidden');
+    
+    try {
+        const preview = await PreviewCrop(buildUploadRequest());
+        if (!preview.success) {
+            showStatusMessage('error', preview.error || '切り抜きのプレビューに失敗しました');
+            return;
+        }
+        
+        const image = document.getElementById('crop-preview-image');
+        if (image.src !== preview.thumbnail) {
+            image.src = preview.thumbnail;
+        }
+        
+        const box = document.getElementById('crop-preview-box');
+        box.style.left = `${preview.cropX * 100}%`;
+        box.style.top = `${preview.cropY * 100}%`;
+        box.style.width = `${preview.cropWidth * 100}%`;
+        box.style.height = `${preview.cropHeight * 100}%`;
+        
+        const marker = document.getElementById('crop-preview-focal');
+        marker.style.left = `${preview.focalX * 100}%`;
+        marker.style.top = `${preview.focalY * 100}%`;
+    } catch (error) {
+        console.error('Crop preview error:', error);
+    }
+}
+
+function handleCropPreviewClick(e) {
+    const rect = e.target.getBoundingClientRect();
+    focalPoint = {
+        x: Math.min(Math.max((e.clientX - rect.left) / rect.width, 0), 1),
+        y: Math.min(Math.max((e.clientY - rect.top) / rect.height, 0), 1)
+    };
+    document.querySelector('input[name="crop-anchor"][value="focal"]').checked = true;
+    updateCropPreview();
 }
 
 async function loadUserInfo() {
@@ -463,8 +1319,12 @@ async function loadUserInfo() {
 
 function updateUserDisplay() {
     const userInfo = document.getElementById('user-info');
-    if (userInfo && currentUser) {
-        userInfo.textContent = `ログイン中: ${currentUser.displayName}`;
+    const logoutBtn = document.getElementById('logout-btn');
+    if (userInfo) {
+        userInfo.textContent = currentUser ? `ログイン中: ${currentUser.displayName}` : '未ログイン（書き出しのみ）';
+    }
+    if (logoutBtn) {
+        logoutBtn.textContent = currentUser ? 'ログアウト' : 'ログイン';
     }
 }
 
@@ -497,6 +1357,193 @@ function showMainScreen() {
     
     updateUserDisplay();
     clearStatusMessage();
+    loadPresets();
+    loadFrames();
+    
+    // The gallery needs an account
+    const gallerySection = document.getElementById('gallery-section');
+    if (gallerySection) gallerySection.classList.toggle('hidden', !currentUser);
+    if (currentUser) {
+        loadGallery();
+    }
+}
+
+// Loads the first page of the user's prints, or the next one if more is set
+async function loadGallery(more = false) {
+    const grid = document.getElementById('gallery-grid');
+    const moreBtn = document.getElementById('gallery-more-btn');
+    const empty = document.getElementById('gallery-empty');
+    if (!grid) return;
+    
+    if (!more) {
+        galleryOffset = 0;
+    }
+    setButtonLoading(moreBtn, true);
+    
+    try {
+        const response = await ListPrints(galleryOffset, 0);
+        if (!response.success) {
+            showStatusMessage('error', response.error || 'プリントの読み込みに失敗しました');
+            return;
+        }
+        
+        const page = response.prints || [];
+        if (!more) {
+            grid.innerHTML = '';
+        }
+        page.forEach((print) => grid.appendChild(renderGalleryItem(print)));
+        galleryOffset += page.length;
+        
+        if (moreBtn) moreBtn.classList.toggle('hidden', !response.hasMore);
+        if (empty) empty.classList.toggle('hidden', grid.children.length > 0);
+    } catch (error) {
+        console.error('Gallery error:', error);
+        showStatusMessage('error', 'プリントの読み込みに失敗しました');
+    } finally {
+        setButtonLoading(moreBtn, false);
+    }
+}
+
+function renderGalleryItem(print) {
+    const item = document.createElement('div');
+    item.className = 'gallery-item';
+    
+    const img = document.createElement('img');
+    img.alt = print.note || print.id;
+    img.loading = 'lazy';
+    item.appendChild(img);
+    loadGalleryImage(img, print.id);
+    
+    if (print.note) {
+        const note = document.createElement('span');
+        note.className = 'gallery-note';
+        note.textContent = print.note;
+        item.appendChild(note);
+    }
+    
+    const details = [];
+    if (print.worldName) details.push(print.worldName);
+    if (print.timestamp) details.push(new Date(print.timestamp).toLocaleString('ja-JP'));
+    if (details.length > 0) {
+        const info = document.createElement('small');
+        info.className = 'form-hint';
+        info.textContent = details.join(' ・ ');
+        item.appendChild(info);
+    }
+    
+    const deleteBtn = document.createElement('button');
+    deleteBtn.type = 'button';
+    deleteBtn.className = 'btn btn-secondary btn-small';
+    deleteBtn.textContent = '削除';
+    deleteBtn.addEventListener('click', () => handlePrintDelete(print, item));
+    item.appendChild(deleteBtn);
+    
+    return item;
+}
+
+async function loadGalleryImage(img, printID) {
+    try {
+        const response = await GetPrintImage(printID);
+        if (response.success) {
+            img.src = response.image;
+        } else {
+            console.error('Print image error:', response.error);
+        }
+    } catch (error) {
+        console.error('Print image error:', error);
+    }
+}
+
+async function handlePrintDelete(print, item) {
+    if (!confirm(`プリント「${print.note || print.id}」を削除しますか？この操作は取り消せません。`)) {
+        return;
+    }
+    
+    const response = await DeletePrint(print.id);
+    if (response.success) {
+        item.remove();
+        galleryOffset = Math.max(0, galleryOffset - 1);
+        const empty = document.getElementById('gallery-empty');
+        const grid = document.getElementById('gallery-grid');
+        if (empty && grid) empty.classList.toggle('hidden', grid.children.length > 0);
+        showStatusMessage('success', 'プリントを削除しました');
+    } else {
+        showStatusMessage('error', response.error || 'プリントの削除に失敗しました');
+    }
+}
+
+// Backs up all prints to dir, or the default backup directory if it is empty
+async function handleBackup(dir) {
+    const backupBtn = document.getElementById('backup-btn');
+    const chooseBtn = document.getElementById('backup-choose-btn');
+    const cancelBtn = document.getElementById('backup-cancel-btn');
+    const progressText = document.getElementById('backup-progress');
+    
+    setButtonLoading(backupBtn, true);
+    if (chooseBtn) chooseBtn.disabled = true;
+    if (cancelBtn) cancelBtn.classList.remove('hidden');
+    if (progressText) {
+        progressText.textContent = 'プリントの一覧を取得中...';
+        progressText.classList.remove('hidden');
+    }
+    
+    try {
+        EventsOn('backup:progress', (progress) => updateBackupProgress(progress));
+        const response = await BackupPrints(dir);
+        EventsOff('backup:progress');
+        
+        const summary = `新規 ${response.downloaded} 件（${formatBytes(response.bytes)}）・既存 ${response.skipped} 件・保存済み合計 ${response.archived} 件`;
+        if (response.success) {
+            let message = `バックアップが完了しました：${summary}`;
+            if (response.failed > 0) {
+                message += `（${response.failed} 件は取得できませんでした。次回再試行します）`;
+            }
+            if (progressText) progressText.textContent = `保存先: ${response.dir}`;
+            showStatusMessage(response.failed > 0 ? 'error' : 'success', message);
+        } else {
+            if (progressText) progressText.classList.add('hidden');
+            const partial = response.downloaded > 0 ? `（${summary}。続きは次回再開します）` : '';
+            showStatusMessage('error', (response.error || 'バックアップに失敗しました') + partial);
+        }
+    } catch (error) {
+        console.error('Backup error:', error);
+        EventsOff('backup:progress');
+        if (progressText) progressText.classList.add('hidden');
+        showStatusMessage('error', 'バックアップに失敗しました');
+    } finally {
+        setButtonLoading(backupBtn, false);
+        if (chooseBtn) chooseBtn.disabled = false;
+        if (cancelBtn) cancelBtn.classList.add('hidden');
+    }
+}
+
+async function handleBackupChoose() {
+    try {
+        const dir = await OpenDirectoryDialog();
+        if (dir) {
+            await handleBackup(dir);
+        }
+    } catch (error) {
+        console.error('Directory dialog error:', error);
+        showStatusMessage('error', 'フォルダの選択に失敗しました');
+    }
+}
+
+function updateBackupProgress(progress) {
+    const progressText = document.getElementById('backup-progress');
+    if (!progressText) return;
+    
+    switch (progress.phase) {
+        case 'listing':
+            progressText.textContent = `プリントの一覧を取得中... (${progress.done} 件)`;
+            break;
+        case 'downloading':
+            progressText.textContent = `画像をダウンロード中... (${progress.done} / ${progress.total})`;
+            break;
+        case 'waiting':
+            progressText.textContent = `APIの制限により ${progress.waitSeconds} 秒待機しています...`;
+            break;
+    }
 }
 
 function show2FASection() {
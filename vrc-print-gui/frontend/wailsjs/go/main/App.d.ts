@@ -1,19 +1,58 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {preset} from '../models';
+
+export function BackupPrints(arg1:string):Promise<main.BackupResponse>;
+
+export function CancelBackup():Promise<boolean>;
+
+export function CancelUpload():Promise<boolean>;
+
+export function DeletePreset(arg1:string):Promise<main.PresetsResponse>;
+
+export function DeletePrint(arg1:string):Promise<main.PrintResponse>;
+
+export function ExportImage(arg1:main.UploadRequest,arg2:string):Promise<main.UploadResponse>;
+
+export function GetBackupDir():Promise<main.BackupDirResponse>;
+
+export function GetCircuitBreakerStatus():Promise<main.CircuitBreakerStatusResponse>;
 
 export function GetCurrentUser():Promise<main.LoginResponse>;
 
+export function GetFrames():Promise<main.FramesResponse>;
+
+export function GetImageMetadata(arg1:string):Promise<main.ImageMetadataResponse>;
+
+export function GetPresets():Promise<main.PresetsResponse>;
+
+export function GetPrint(arg1:string):Promise<main.PrintResponse>;
+
+export function GetPrintImage(arg1:string):Promise<main.PrintImageResponse>;
+
+export function GetRateLimitStatus():Promise<main.RateLimitStatusResponse>;
+
 export function IsAuthenticated():Promise<boolean>;
 
+export function ListPrints(arg1:number,arg2:number):Promise<main.PrintsResponse>;
+
 export function Login(arg1:main.LoginRequest):Promise<main.LoginResponse>;
 
 export function Logout():Promise<main.LoginResponse>;
 
+export function OpenDirectoryDialog():Promise<string>;
+
 export function OpenFileDialog():Promise<string>;
 
+export function PreviewCrop(arg1:main.UploadRequest):Promise<main.CropPreviewResponse>;
+
+export function PreviewImage(arg1:main.UploadRequest):Promise<main.ImagePreviewResponse>;
+
+export function SavePreset(arg1:preset.Preset):Promise<main.PresetsResponse>;
+
 export function UploadImage(arg1:main.UploadRequest):Promise<main.UploadResponse>;
 
-export function ValidateImageFile(arg1:string):Promise<Record<string, any>>;
+export function ValidateImageFile(arg1:main.UploadRequest):Promise<main.ImageValidationResponse>;
 
 export function VerifyTwoFactor(arg1:main.TwoFactorRequest):Promise<main.LoginResponse>;
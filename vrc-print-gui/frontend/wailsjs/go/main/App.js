@@ -2,14 +2,74 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function BackupPrints(arg1) {
+  return window['go']['main']['App']['BackupPrints'](arg1);
+}
+
+export function CancelBackup() {
+  return window['go']['main']['App']['CancelBackup']();
+}
+
+export function CancelUpload() {
+  return window['go']['main']['App']['CancelUpload']();
+}
+
+export function DeletePreset(arg1) {
+  return window['go']['main']['App']['DeletePreset'](arg1);
+}
+
+export function DeletePrint(arg1) {
+  return window['go']['main']['App']['DeletePrint'](arg1);
+}
+
+export function ExportImage(arg1,arg2) {
+  return window['go']['main']['App']['ExportImage'](arg1, arg2);
+}
+
+export function GetBackupDir() {
+  return window['go']['main']['App']['GetBackupDir']();
+}
+
+export function GetCircuitBreakerStatus() {
+  return window['go']['main']['App']['GetCircuitBreakerStatus']();
+}
+
 export function GetCurrentUser() {
   return window['go']['main']['App']['GetCurrentUser']();
 }
 
+export function GetFrames() {
+  return window['go']['main']['App']['GetFrames']();
+}
+
+export function GetImageMetadata(arg1) {
+  return window['go']['main']['App']['GetImageMetadata'](arg1);
+}
+
+export function GetPresets() {
+  return window['go']['main']['App']['GetPresets']();
+}
+
+export function GetPrint(arg1) {
+  return window['go']['main']['App']['GetPrint'](arg1);
+}
+
+export function GetPrintImage(arg1) {
+  return window['go']['main']['App']['GetPrintImage'](arg1);
+}
+
+export function GetRateLimitStatus() {
+  return window['go']['main']['App']['GetRateLimitStatus']();
+}
+
 export function IsAuthenticated() {
   return window['go']['main']['App']['IsAuthenticated']();
 }
 
+export function ListPrints(arg1,arg2) {
+  return window['go']['main']['App']['ListPrints'](arg1, arg2);
+}
+
 export function Login(arg1) {
   return window['go']['main']['App']['Login'](arg1);
 }
@@ -18,10 +78,26 @@ export function Logout() {
   return window['go']['main']['App']['Logout']();
 }
 
+export function OpenDirectoryDialog() {
+  return window['go']['main']['App']['OpenDirectoryDialog']();
+}
+
 export function OpenFileDialog() {
   return window['go']['main']['App']['OpenFileDialog']();
 }
 
+export function PreviewCrop(arg1) {
+  return window['go']['main']['App']['PreviewCrop'](arg1);
+}
+
+export function PreviewImage(arg1) {
+  return window['go']['main']['App']['PreviewImage'](arg1);
+}
+
+export function SavePreset(arg1) {
+  return window['go']['main']['App']['SavePreset'](arg1);
+}
+
 export function UploadImage(arg1) {
   return window['go']['main']['App']['UploadImage'](arg1);
 }
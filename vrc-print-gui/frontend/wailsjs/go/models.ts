@@ -1,5 +1,211 @@
 export namespace main {
 	
+	export class BackupDirResponse {
+	    success: boolean;
+	    error?: string;
+	    dir?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupDirResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.dir = source["dir"];
+	    }
+	}
+	export class BackupResponse {
+	    success: boolean;
+	    error?: string;
+	    dir: string;
+	    listed: number;
+	    downloaded: number;
+	    skipped: number;
+	    failed: number;
+	    archived: number;
+	    bytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.dir = source["dir"];
+	        this.listed = source["listed"];
+	        this.downloaded = source["downloaded"];
+	        this.skipped = source["skipped"];
+	        this.failed = source["failed"];
+	        this.archived = source["archived"];
+	        this.bytes = source["bytes"];
+	    }
+	}
+	export class CircuitBreakerStatusResponse {
+	    state: string;
+	    available: boolean;
+	    consecutiveFailures: number;
+	    retryAfterSeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CircuitBreakerStatusResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.state = source["state"];
+	        this.available = source["available"];
+	        this.consecutiveFailures = source["consecutiveFailures"];
+	        this.retryAfterSeconds = source["retryAfterSeconds"];
+	    }
+	}
+	export class CropPreviewResponse {
+	    success: boolean;
+	    error?: string;
+	    thumbnail?: string;
+	    width: number;
+	    height: number;
+	    cropX: number;
+	    cropY: number;
+	    cropWidth: number;
+	    cropHeight: number;
+	    focalX: number;
+	    focalY: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CropPreviewResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.thumbnail = source["thumbnail"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.cropX = source["cropX"];
+	        this.cropY = source["cropY"];
+	        this.cropWidth = source["cropWidth"];
+	        this.cropHeight = source["cropHeight"];
+	        this.focalX = source["focalX"];
+	        this.focalY = source["focalY"];
+	    }
+	}
+	export class FramesResponse {
+	    success: boolean;
+	    error?: string;
+	    frames: upload.FrameTemplate[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FramesResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.frames = this.convertValues(source["frames"], upload.FrameTemplate);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImageMetadataResponse {
+	    success: boolean;
+	    error?: string;
+	    worldId?: string;
+	    worldName?: string;
+	    author?: string;
+	    captureTime?: string;
+	    worldFromLog: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageMetadataResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.worldId = source["worldId"];
+	        this.worldName = source["worldName"];
+	        this.author = source["author"];
+	        this.captureTime = source["captureTime"];
+	        this.worldFromLog = source["worldFromLog"];
+	    }
+	}
+	export class ImagePreviewResponse {
+	    success: boolean;
+	    error?: string;
+	    image?: string;
+	    width: number;
+	    height: number;
+	    size: number;
+	    strategy?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImagePreviewResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.image = source["image"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.size = source["size"];
+	        this.strategy = source["strategy"];
+	    }
+	}
+	export class ImageValidationResponse {
+	    valid: boolean;
+	    error?: string;
+	    format?: string;
+	    width: number;
+	    height: number;
+	    fileSize: number;
+	    colorModel?: string;
+	    frames: number;
+	    outputWidth: number;
+	    outputHeight: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageValidationResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.valid = source["valid"];
+	        this.error = source["error"];
+	        this.format = source["format"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.fileSize = source["fileSize"];
+	        this.colorModel = source["colorModel"];
+	        this.frames = source["frames"];
+	        this.outputWidth = source["outputWidth"];
+	        this.outputHeight = source["outputHeight"];
+	    }
+	}
 	export class LoginRequest {
 	    username: string;
 	    password: string;
@@ -34,6 +240,146 @@ export namespace main {
 	        this.errors = source["errors"];
 	    }
 	}
+	export class PresetsResponse {
+	    success: boolean;
+	    error?: string;
+	    presets: preset.Preset[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PresetsResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.presets = this.convertValues(source["presets"], preset.Preset);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PrintImageResponse {
+	    success: boolean;
+	    error?: string;
+	    image?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PrintImageResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.image = source["image"];
+	    }
+	}
+	export class PrintResponse {
+	    success: boolean;
+	    error?: string;
+	    print?: prints.Print;
+	
+	    static createFrom(source: any = {}) {
+	        return new PrintResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.print = this.convertValues(source["print"], prints.Print);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PrintsResponse {
+	    success: boolean;
+	    error?: string;
+	    prints: prints.Print[];
+	    hasMore: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PrintsResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.prints = this.convertValues(source["prints"], prints.Print);
+	        this.hasMore = source["hasMore"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RateLimitStatusResponse {
+	    limited: boolean;
+	    retryAfterSeconds: number;
+	    limit: number;
+	    remaining: number;
+	    resetAt?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RateLimitStatusResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.limited = source["limited"];
+	        this.retryAfterSeconds = source["retryAfterSeconds"];
+	        this.limit = source["limit"];
+	        this.remaining = source["remaining"];
+	        this.resetAt = source["resetAt"];
+	    }
+	}
 	export class TwoFactorRequest {
 	    code: string;
 	    isRecoveryCode: boolean;
@@ -53,7 +399,24 @@ export namespace main {
 	    note: string;
 	    worldId: string;
 	    worldName: string;
-	    noResize: boolean;
+	    captureTime?: string;
+	    timestamp?: string;
+	    timeZone?: string;
+	    resizeMode: string;
+	    padColor?: string;
+	    cropAnchor?: string;
+	    focalX: number;
+	    focalY: number;
+	    rotate: number;
+	    flipHorizontal: boolean;
+	    flipVertical: boolean;
+	    shrink: boolean;
+	    quantize: boolean;
+	    preserveAlpha: boolean;
+	    backgroundColor?: string;
+	    filters: upload.FilterSpec[];
+	    frame?: string;
+	    watermarks: upload.Watermark[];
 	
 	    static createFrom(source: any = {}) {
 	        return new UploadRequest(source);
@@ -65,14 +428,55 @@ export namespace main {
 	        this.note = source["note"];
 	        this.worldId = source["worldId"];
 	        this.worldName = source["worldName"];
-	        this.noResize = source["noResize"];
+	        this.captureTime = source["captureTime"];
+	        this.timestamp = source["timestamp"];
+	        this.timeZone = source["timeZone"];
+	        this.resizeMode = source["resizeMode"];
+	        this.padColor = source["padColor"];
+	        this.cropAnchor = source["cropAnchor"];
+	        this.focalX = source["focalX"];
+	        this.focalY = source["focalY"];
+	        this.rotate = source["rotate"];
+	        this.flipHorizontal = source["flipHorizontal"];
+	        this.flipVertical = source["flipVertical"];
+	        this.shrink = source["shrink"];
+	        this.quantize = source["quantize"];
+	        this.preserveAlpha = source["preserveAlpha"];
+	        this.backgroundColor = source["backgroundColor"];
+	        this.filters = this.convertValues(source["filters"], upload.FilterSpec);
+	        this.frame = source["frame"];
+	        this.watermarks = this.convertValues(source["watermarks"], upload.Watermark);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class UploadResponse {
 	    success: boolean;
 	    message: string;
 	    fileId?: string;
 	    error?: string;
+	    strategy?: string;
+	    width?: number;
+	    height?: number;
+	    size?: number;
+	    exportPath?: string;
+	    manifestPath?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new UploadResponse(source);
@@ -84,6 +488,277 @@ export namespace main {
 	        this.message = source["message"];
 	        this.fileId = source["fileId"];
 	        this.error = source["error"];
+	        this.strategy = source["strategy"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.size = source["size"];
+	        this.exportPath = source["exportPath"];
+	        this.manifestPath = source["manifestPath"];
+	    }
+	}
+
+}
+
+export namespace preset {
+	
+	export class Preset {
+	    name: string;
+	    resizeMode: string;
+	    padColor?: string;
+	    cropAnchor?: string;
+	    rotate: number;
+	    flipHorizontal: boolean;
+	    flipVertical: boolean;
+	    preserveAlpha: boolean;
+	    backgroundColor?: string;
+	    shrink: boolean;
+	    quantize: boolean;
+	    filters: upload.FilterSpec[];
+	    frame?: string;
+	    watermarks: upload.Watermark[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.resizeMode = source["resizeMode"];
+	        this.padColor = source["padColor"];
+	        this.cropAnchor = source["cropAnchor"];
+	        this.rotate = source["rotate"];
+	        this.flipHorizontal = source["flipHorizontal"];
+	        this.flipVertical = source["flipVertical"];
+	        this.preserveAlpha = source["preserveAlpha"];
+	        this.backgroundColor = source["backgroundColor"];
+	        this.shrink = source["shrink"];
+	        this.quantize = source["quantize"];
+	        this.filters = this.convertValues(source["filters"], upload.FilterSpec);
+	        this.frame = source["frame"];
+	        this.watermarks = this.convertValues(source["watermarks"], upload.Watermark);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace prints {
+	
+	export class Files {
+	    fileId: string;
+	    image: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Files(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fileId = source["fileId"];
+	        this.image = source["image"];
+	    }
+	}
+	export class Print {
+	    id: string;
+	    authorId: string;
+	    authorName: string;
+	    ownerId: string;
+	    note: string;
+	    worldId: string;
+	    worldName: string;
+	    timestamp: any;
+	    createdAt: any;
+	    files: prints.Files;
+	
+	    static createFrom(source: any = {}) {
+	        return new Print(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.authorId = source["authorId"];
+	        this.authorName = source["authorName"];
+	        this.ownerId = source["ownerId"];
+	        this.note = source["note"];
+	        this.worldId = source["worldId"];
+	        this.worldName = source["worldName"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.files = this.convertValues(source["files"], Files);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace upload {
+	
+	export class FilterSpec {
+	    type: string;
+	    angle?: number;
+	    x?: number;
+	    y?: number;
+	    width?: number;
+	    height?: number;
+	    amount?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FilterSpec(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.angle = source["angle"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.amount = source["amount"];
+	    }
+	}
+	export class FrameBorder {
+	    top: number;
+	    right: number;
+	    bottom: number;
+	    left: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameBorder(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.top = source["top"];
+	        this.right = source["right"];
+	        this.bottom = source["bottom"];
+	        this.left = source["left"];
+	    }
+	}
+	export class FrameCaption {
+	    x?: number;
+	    y?: number;
+	    width?: number;
+	    height?: number;
+	    color?: string;
+	    align?: string;
+	    lines?: string[];
+	    dateFormat?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameCaption(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.color = source["color"];
+	        this.align = source["align"];
+	        this.lines = source["lines"];
+	        this.dateFormat = source["dateFormat"];
+	    }
+	}
+	export class FrameTemplate {
+	    name: string;
+	    description?: string;
+	    border: upload.FrameBorder;
+	    color?: string;
+	    backgroundImage?: string;
+	    caption?: upload.FrameCaption;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameTemplate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.border = this.convertValues(source["border"], FrameBorder);
+	        this.color = source["color"];
+	        this.backgroundImage = source["backgroundImage"];
+	        this.caption = this.convertValues(source["caption"], FrameCaption);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (Array.isArray(a)) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Watermark {
+	    text?: string;
+	    imagePath?: string;
+	    position?: string;
+	    margin?: number;
+	    opacity?: number;
+	    scale?: number;
+	    color?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Watermark(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.imagePath = source["imagePath"];
+	        this.position = source["position"];
+	        this.margin = source["margin"];
+	        this.opacity = source["opacity"];
+	        this.scale = source["scale"];
+	        this.color = source["color"];
 	    }
 	}
 
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/yoshiken/vrc-print-upload/internal/prints"
+)
+
+// galleryThumbnailSize is the longest side of gallery thumbnails
+const galleryThumbnailSize = 400
+
+// PrintsResponse is a page of the user's prints
+type PrintsResponse struct {
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+	Prints  []prints.Print `json:"prints"`
+	// HasMore is set when the page was full, so there may be more prints
+	HasMore bool `json:"hasMore"`
+}
+
+// PrintResponse is the result of an operation on a single print
+type PrintResponse struct {
+	Success bool          `json:"success"`
+	Error   string        `json:"error,omitempty"`
+	Print   *prints.Print `json:"print,omitempty"`
+}
+
+// PrintImageResponse carries a thumbnail of a print's image
+type PrintImageResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Image is a data URL of the downscaled image
+	Image string `json:"image,omitempty"`
+}
+
+// ListPrints returns a page of the logged in user's prints, newest first.
+// A limit of 0 uses the default page size.
+func (a *App) ListPrints(offset, limit int) PrintsResponse {
+	if a.printService == nil {
+		return PrintsResponse{
+			Success: false,
+			Error:   "Not authenticated. Please log in first.",
+		}
+	}
+
+	userID, err := a.currentUserID()
+	if err != nil {
+		return PrintsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to get user info: %v", err),
+		}
+	}
+
+	opts := prints.ListOptions{Offset: offset, Limit: limit}
+	page, err := a.printService.List(a.ctx, userID, opts)
+	if err != nil {
+		return PrintsResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to load prints: %v", err),
+		}
+	}
+
+	a.galleryMu.Lock()
+	if a.gallery == nil {
+		a.gallery = map[string]prints.Print{}
+	}
+	for _, p := range page {
+		a.gallery[p.ID] = p
+	}
+	a.galleryMu.Unlock()
+
+	return PrintsResponse{
+		Success: true,
+		Prints:  page,
+		HasMore: len(page) >= opts.PageSize(),
+	}
+}
+
+// GetPrint fetches a single print
+func (a *App) GetPrint(printID string) PrintResponse {
+	if a.printService == nil {
+		return PrintResponse{
+			Success: false,
+			Error:   "Not authenticated. Please log in first.",
+		}
+	}
+
+	p, err := a.lookupPrint(printID, true)
+	if err != nil {
+		return PrintResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to load print: %v", err),
+		}
+	}
+	return PrintResponse{
+		Success: true,
+		Print:   p,
+	}
+}
+
+// GetPrintImage downloads the image of a print and returns a thumbnail of
+// it. The image URL needs the session cookies, so the webview can't load it
+// itself.
+func (a *App) GetPrintImage(printID string) PrintImageResponse {
+	if a.printService == nil {
+		return PrintImageResponse{
+			Success: false,
+			Error:   "Not authenticated. Please log in first.",
+		}
+	}
+
+	p, err := a.lookupPrint(printID, false)
+	if err != nil {
+		return PrintImageResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to load print: %v", err),
+		}
+	}
+
+	thumb, err := a.printService.Thumbnail(a.ctx, *p, galleryThumbnailSize)
+	if err != nil {
+		return PrintImageResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to load print image: %v", err),
+		}
+	}
+	return PrintImageResponse{
+		Success: true,
+		Image:   "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb),
+	}
+}
+
+// DeletePrint deletes one of the user's prints
+func (a *App) DeletePrint(printID string) PrintResponse {
+	if a.printService == nil {
+		return PrintResponse{
+			Success: false,
+			Error:   "Not authenticated. Please log in first.",
+		}
+	}
+
+	err := a.printService.Delete(a.ctx, printID)
+	if err == nil || errors.Is(err, prints.ErrNotFound) {
+		// A print that is already gone shouldn't stay in the gallery either
+		a.galleryMu.Lock()
+		delete(a.gallery, printID)
+		a.galleryMu.Unlock()
+	}
+	if err != nil {
+		return PrintResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to delete print: %v", err),
+		}
+	}
+
+	return PrintResponse{Success: true}
+}
+
+// currentUserID returns the ID of the logged in user. The session may have
+// been restored at startup without a login, so it is fetched if unknown.
+func (a *App) currentUserID() (string, error) {
+	a.userMu.Lock()
+	userID := a.userID
+	a.userMu.Unlock()
+	if userID != "" {
+		return userID, nil
+	}
+
+	user, err := a.authClient.GetCurrentUser(a.ctx)
+	if err != nil {
+		return "", err
+	}
+	a.setUser(user.ID, user.DisplayName)
+	return user.ID, nil
+}
+
+// lookupPrint returns a print from the listed ones, or fetches it if it
+// hasn't been listed or refresh is set
+func (a *App) lookupPrint(printID string, refresh bool) (*prints.Print, error) {
+	if !refresh {
+		a.galleryMu.Lock()
+		p, ok := a.gallery[printID]
+		a.galleryMu.Unlock()
+		if ok {
+			return &p, nil
+		}
+	}
+
+	p, err := a.printService.Get(a.ctx, printID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.galleryMu.Lock()
+	if a.gallery == nil {
+		a.gallery = map[string]prints.Print{}
+	}
+	a.gallery[p.ID] = *p
+	a.galleryMu.Unlock()
+	return p, nil
+}
+
+// resetGallery forgets the listed prints, e.g. when the user logs out
+func (a *App) resetGallery() {
+	a.galleryMu.Lock()
+	defer a.galleryMu.Unlock()
+	a.gallery = nil
+}
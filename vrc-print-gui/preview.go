@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yoshiken/vrc-print-upload/internal/upload"
+)
+
+// processedPreviewSize is the longest side of processed image previews
+const processedPreviewSize = 800
+
+// previewCacheSize is how many processed previews are kept
+const previewCacheSize = 8
+
+// ImagePreviewResponse shows the selected image as it would be uploaded
+type ImagePreviewResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Image is a data URL of the downscaled processed image
+	Image string `json:"image,omitempty"`
+	// Width, Height and Size describe the PNG that would be uploaded
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Size     int    `json:"size"`
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// previewCache keeps the most recently used previews by input and options
+type previewCache struct {
+	mu      sync.Mutex
+	keys    []string
+	entries map[string]*upload.ImagePreview
+}
+
+func newPreviewCache() *previewCache {
+	return &previewCache{entries: map[string]*upload.ImagePreview{}}
+}
+
+// get returns the cached preview for key and marks it as recently used
+func (c *previewCache) get(key string) (*upload.ImagePreview, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	preview, ok := c.entries[key]
+	if ok {
+		c.touch(key)
+	}
+	return preview, ok
+}
+
+// put adds a preview, evicting the least recently used one when full
+func (c *previewCache) put(key string, preview *upload.ImagePreview) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.keys) >= previewCacheSize {
+		delete(c.entries, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.entries[key] = preview
+	c.touch(key)
+}
+
+// touch moves key to the most recently used end
+func (c *previewCache) touch(key string) {
+	for i, k := range c.keys {
+		if k == key {
+			c.keys = append(c.keys[:i], c.keys[i+1:]...)
+			break
+		}
+	}
+	c.keys = append(c.keys, key)
+}
+
+// previewKey identifies a preview by the content of the image file, the
+// versions of the images drawn over it and everything in the options that
+// affects the uploaded PNG
+func previewKey(req UploadRequest, opts upload.Options) (string, error) {
+	file, err := os.Open(opts.ImagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	input := sha256.New()
+	if _, err := io.Copy(input, file); err != nil {
+		return "", fmt.Errorf("failed to read image file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file: %w", err)
+	}
+
+	// The request names the frame, so include the template itself in case
+	// its file was edited. The modification time can end up in the
+	// metadata.
+	options, err := json.Marshal(struct {
+		Request  UploadRequest
+		Frame    *upload.FrameTemplate
+		Metadata upload.MetadataOptions
+		ModTime  time.Time
+		Overlays []overlayFile
+	}{req, opts.Frame, opts.Metadata, info.ModTime(), overlayFiles(opts)})
+	if err != nil {
+		return "", err
+	}
+
+	key := sha256.New()
+	key.Write(input.Sum(nil))
+	key.Write(options)
+	return hex.EncodeToString(key.Sum(nil)), nil
+}
+
+// overlayFile identifies a version of an image drawn over the upload
+type overlayFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// overlayFiles lists the watermark images and the frame background in opts,
+// so that editing one of them invalidates the preview. Files that can't be
+// read are listed by path only; processing reports the error.
+func overlayFiles(opts upload.Options) []overlayFile {
+	var paths []string
+	for _, w := range opts.Watermarks {
+		if w.ImagePath != "" {
+			paths = append(paths, w.ImagePath)
+		}
+	}
+	if opts.Frame != nil && opts.Frame.BackgroundImage != "" {
+		paths = append(paths, opts.Frame.BackgroundImage)
+	}
+
+	files := make([]overlayFile, 0, len(paths))
+	for _, path := range paths {
+		file := overlayFile{Path: path}
+		if info, err := os.Stat(path); err == nil {
+			file.Size = info.Size()
+			file.ModTime = info.ModTime()
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// PreviewImage runs the upload processing with the requested options and
+// returns a downscaled image of the result with the dimensions and size of
+// the PNG that would be uploaded. Results are cached by the content of the
+// image file and the options.
+func (a *App) PreviewImage(req UploadRequest) ImagePreviewResponse {
+	opts, err := a.uploadOptions(req)
+	if err != nil {
+		return ImagePreviewResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	key, err := previewKey(req, opts)
+	if err != nil {
+		return ImagePreviewResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	preview, ok := a.previews.get(key)
+	if !ok {
+		preview, err = upload.PreviewImage(a.ctx, opts, processedPreviewSize)
+		if err != nil {
+			return ImagePreviewResponse{
+				Success: false,
+				Error:   fmt.Sprintf("Failed to preview image: %v", err),
+			}
+		}
+		a.previews.put(key, preview)
+	}
+
+	return ImagePreviewResponse{
+		Success:  true,
+		Image:    "data:" + preview.ThumbnailType + ";base64," + base64.StdEncoding.EncodeToString(preview.Thumbnail),
+		Width:    preview.Width,
+		Height:   preview.Height,
+		Size:     preview.Size,
+		Strategy: string(preview.Strategy),
+	}
+}